@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Permission is a set of operations a user may perform on their files.
+type Permission uint8
+
+const (
+	Read   Permission = 1 << iota // get and list
+	Write                         // put
+	Delete                        // delete
+)
+
+func (p Permission) String() string {
+	var names []string
+	if p&Read != 0 {
+		names = append(names, "read")
+	}
+	if p&Write != 0 {
+		names = append(names, "write")
+	}
+	if p&Delete != 0 {
+		names = append(names, "delete")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// ACL grants permissions to users and groups. A user's permissions are
+// everything granted to them by name, to any of their groups, and to "*".
+// A nil ACL allows everything.
+type ACL struct {
+	rules map[string]Permission
+}
+
+// LoadACL reads an ACL file with one "subject permissions" rule per line. The
+// subject is a user name, @group or * for everyone (including clients of a
+// server without credentials), and permissions is some combination of the
+// letters r, w and d, or - for none. Blank lines and lines starting with #
+// are ignored.
+func LoadACL(path string) (*ACL, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	a := &ACL{rules: make(map[string]Permission)}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"subject permissions\"", path, lineNo)
+		}
+		perm, err := parsePermission(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		a.rules[fields[0]] |= perm
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Allows reports whether user may perform every operation in perm. A nil user
+// is an anonymous client, which only gets what is granted to "*".
+func (a *ACL) Allows(user *User, perm Permission) bool {
+	if a == nil {
+		return true
+	}
+
+	granted := a.rules["*"]
+	if user != nil {
+		granted |= a.rules[user.Name]
+		for _, group := range user.Groups {
+			granted |= a.rules["@"+group]
+		}
+	}
+	return granted&perm == perm
+}
+
+func parsePermission(letters string) (Permission, error) {
+	if letters == "-" {
+		return 0, nil
+	}
+
+	var perm Permission
+	for _, c := range letters {
+		switch c {
+		case 'r':
+			perm |= Read
+		case 'w':
+			perm |= Write
+		case 'd':
+			perm |= Delete
+		default:
+			return 0, fmt.Errorf("unknown permission %q", c)
+		}
+	}
+	return perm, nil
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// User is a client identified by its token.
+type User struct {
+	Name   string
+	Groups []string
+}
+
+// Credentials maps client tokens to users. Tokens are kept hashed so a lookup
+// doesn't compare secrets byte by byte.
+type Credentials struct {
+	users map[[sha256.Size]byte]*User
+}
+
+// LoadCredentials reads a credentials file with one "token user [groups]"
+// entry per line, where groups is a comma separated list. Blank lines and
+// lines starting with # are ignored. User names double as the names of the
+// users' directories, so they can't contain slashes or start with a dot.
+func LoadCredentials(path string) (*Credentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	c := &Credentials{users: make(map[[sha256.Size]byte]*User)}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected \"token user [groups]\"", path, lineNo)
+		}
+		if !validName(fields[1]) {
+			return nil, fmt.Errorf("%s:%d: invalid user name %q", path, lineNo, fields[1])
+		}
+
+		user := &User{Name: fields[1]}
+		if len(fields) == 3 {
+			user.Groups = strings.Split(fields[2], ",")
+		}
+		c.users[sha256.Sum256([]byte(fields[0]))] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Authenticate returns the user a token belongs to.
+func (c *Credentials) Authenticate(token string) (*User, bool) {
+	user, ok := c.users[sha256.Sum256([]byte(token))]
+	return user, ok
+}
+
+func validName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
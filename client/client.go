@@ -1,116 +1,717 @@
-package main
+// Package client transfers files to and from a file-transfer server. It is
+// what the command line clients are built on, and can be used to embed
+// transfers in other programs.
+package client
 
 import (
-	"crypto/md5"
-	"file-transfer/messages"
-	"file-transfer/util"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"log"
+	"math"
 	"net"
 	"os"
-	"strings"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"file-transfer/messages"
+	"file-transfer/util"
 )
 
-func put(msgHandler *messages.MessageHandler, fileName string) int {
-	fmt.Println("PUT", fileName)
+// Config holds the settings used to connect to a server.
+type Config struct {
+	TLS   *tls.Config            // nil for plain TCP
+	Token string                 // Sent to servers that require authentication; empty to skip
+	Hash  messages.HashAlgorithm // Checksum algorithm for transfers; the zero value is MD5
+
+	// What the server does when a Put names a file it already holds; the zero
+	// value refuses the upload
+	Conflict messages.ConflictPolicy
+
+	// Read each file an extra time before a Put to tell the server its
+	// SHA-256, so a deduplicating server that already has the content can
+	// skip the upload
+	Dedup bool
+
+	// Compress file data on the wire, if the server supports it; checksums
+	// still cover the uncompressed content
+	Compression messages.Compression
+
+	// Send file data as checksummed chunks, if the server supports it, so a
+	// transfer can be aborted, or corruption found, without dropping the
+	// connection
+	Chunked bool
+
+	// Number of connections Put and Get split large files over, for links
+	// where one TCP connection can't keep the pipe full; 0 or 1 for just the
+	// Client's own
+	Streams int
+
+	// How long to wait on the server, on top of the deadlines of the contexts
+	// passed in; zero values wait forever. Idle covers waiting for replies,
+	// which may take a while for requests that have the server read a whole
+	// large file, such as putting one together from ranges.
+	Timeouts messages.Timeouts
+}
+
+// Stored describes where the server put an upload.
+type Stored struct {
+	Name    string // Differs from the name asked for with KEEP_BOTH
+	Version uint32 // 0 unless the server keeps versions of the file
+}
+
+func (s *Stored) String() string {
+	if s.Version > 0 {
+		return fmt.Sprintf("%s (version %d)", s.Name, s.Version)
+	}
+	return s.Name
+}
+
+// Stats counts the file data a Client has transferred.
+type Stats struct {
+	Raw  int64 // Bytes of file content sent and received
+	Wire int64 // Bytes that went over the connection for them, after compression
+}
+
+func (s Stats) String() string {
+	if s.Raw == 0 {
+		return fmt.Sprintf("Transferred %d bytes", s.Raw)
+	}
+	return fmt.Sprintf("Transferred %d bytes as %d on the wire (%.1f%%)", s.Raw, s.Wire, 100*float64(s.Wire)/float64(s.Raw))
+}
+
+// Client is a connection to a server. Requests are sent one at a time, so a
+// Client must not be used from several goroutines at once. If a context is
+// cancelled in the middle of a request the connection is left in an unknown
+// state and the Client should be closed.
+type Client struct {
+	host       string
+	config     *Config // For dialing extra streams
+	conn       net.Conn
+	msgHandler *messages.MessageHandler
+	hash       messages.HashAlgorithm
+	conflict   messages.ConflictPolicy
+	dedup      bool
+	compress   messages.Compression
+	chunked    bool
+	ranges     bool            // Whether to try uploading in ranges
+	server     *messages.Hello // nil for servers from before the handshake
+	legacy     bool            // The server is from before the handshake
+	stats      Stats
+	user       string
+}
+
+// Dial connects to a server at host ("address:port"), finds out what it
+// supports and authenticates if config has a token. A nil config means plain
+// TCP, no token and MD5. Dial fails with ErrUnsupported if the server can't
+// speak the same protocol version or use config's checksum algorithm; other
+// settings the server doesn't support are quietly left out of transfers.
+func Dial(ctx context.Context, host string, config *Config) (*Client, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	if _, err := util.NewHash(config.Hash); err != nil {
+		return nil, err
+	}
+
+	c, err := dial(ctx, host, config, true)
+	if errors.Is(err, errNoHello) {
+		// Servers from before the handshake hang up on it, and only know MD5
+		if config.Hash != messages.HashAlgorithm_MD5 {
+			return nil, fmt.Errorf("server from before the handshake can't checksum with %s: %w", config.Hash, ErrUnsupported)
+		}
+		c, err = dial(ctx, host, config, false)
+	}
+	return c, err
+}
+
+// errNoHello means the server doesn't know the Hello handshake.
+var errNoHello = errors.New("server doesn't know the handshake")
+
+// legacyReplyTimeout is how long a server from before the handshake gets to
+// reply if Config.Timeouts doesn't say. Such servers don't answer the
+// checksum of an upload, so without it a Put would wait forever.
+const legacyReplyTimeout = 10 * time.Second
+
+// dial makes a connection for Dial, with or without the handshake.
+func dial(ctx context.Context, host string, config *Config, hello bool) (*Client, error) {
+	conn, err := util.Dial(ctx, host, config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		host:       host,
+		config:     config,
+		conn:       conn,
+		msgHandler: messages.NewMessageHandler(conn),
+		hash:       config.Hash,
+		conflict:   config.Conflict,
+		dedup:      config.Dedup,
+		compress:   config.Compression,
+		chunked:    config.Chunked,
+		ranges:     true,
+		legacy:     !hello,
+	}
+
+	timeouts := config.Timeouts
+	if c.legacy && timeouts.Idle == 0 {
+		timeouts.Idle = legacyReplyTimeout
+	}
+	c.msgHandler.SetTimeouts(timeouts)
+
+	if hello {
+		if err := c.hello(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if config.Token != "" {
+		if err := c.authenticate(ctx, config.Token); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Stats returns the amount of file data transferred so far.
+func (c *Client) Stats() Stats {
+	return c.stats
+}
+
+// ServerHello returns what the server said it supports, or nil for servers
+// too old to say.
+func (c *Client) ServerHello() *messages.Hello {
+	return c.server
+}
+
+// User returns the name the server authenticated the client as, or "" if no
+// token was sent or the server doesn't require one.
+func (c *Client) User() string {
+	return c.user
+}
 
-	// Get file size and make sure it exists
-	info, err := os.Stat(fileName)
+// Put uploads the file at localPath, storing it as remoteName with the same
+// permissions and modification time. If an earlier upload of the same file
+// was interrupted, only the rest of it is sent. With Config.Streams, a large
+// file is sent in ranges over several connections instead, unless the server
+// can't put them together or Config.Dedup is set.
+func (c *Client) Put(ctx context.Context, localPath string, remoteName string) (*Stored, error) {
+	file, err := os.Open(localPath)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s: %w", localPath, ErrNotRegular)
+	}
+
+	var sum []byte
+	if c.dedup {
+		sum, err = util.HashFile(localPath, messages.HashAlgorithm_SHA256)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	defer c.watch(ctx)()
+	if ranges := splitRanges(info.Size(), c.config.Streams); len(ranges) > 1 && c.ranges && !c.dedup {
+		stored, err := c.putRanges(ctx, file, remoteName, info.Size(), util.FileMode(info), info.ModTime().Unix(), ranges)
+		return stored, c.contextErr(ctx, err)
+	}
+	stored, err := c.put(file, remoteName, info.Size(), util.FileMode(info), info.ModTime().Unix(), sum)
+	return stored, c.contextErr(ctx, err)
+}
+
+// PutReader uploads size bytes read from r, storing them as remoteName. If the
+// server already holds part of the file from an interrupted upload, those
+// bytes are still read from r (to checksum them) but not sent again; that has
+// to be done before the server's stall timeout runs out.
+func (c *Client) PutReader(ctx context.Context, r io.Reader, size int64, remoteName string) (*Stored, error) {
+	defer c.watch(ctx)()
+	// The data starts wherever r is, so it mustn't be read at other offsets
+	stored, err := c.put(struct{ io.Reader }{r}, remoteName, size, 0, 0, nil)
+	return stored, c.contextErr(ctx, err)
+}
+
+// Get downloads remoteName to localPath, which must not exist yet. The data
+// goes to a hidden partial file first and is only moved into place once its
+// checksum matches and it is safely on disk, so a failed Get never leaves a
+// partial file under localPath, and an interrupted one can be resumed by
+// calling it again.
+func (c *Client) Get(ctx context.Context, remoteName string, localPath string) error {
+	return c.GetVersion(ctx, remoteName, 0, localPath)
+}
+
+// GetVersion is Get for an older version of a file the server keeps versions
+// of. Versions are numbered from 1, oldest first; 0 means the current one.
+func (c *Client) GetVersion(ctx context.Context, remoteName string, version uint32, localPath string) error {
+	if _, err := os.Lstat(localPath); err == nil {
+		return fmt.Errorf("%s: %w", localPath, ErrExists)
+	}
+
+	// Continue an interrupted download, checksumming what we already have
+	h, _ := util.NewHash(c.hash)
+	file, offset, err := util.OpenPartial(localPath, math.MaxUint64, h)
+	if err != nil {
+		return err
+	}
+	partial := util.PartialName(localPath)
+
+	stop := c.watch(ctx)
+	if ranges, whole := c.downloadRanges(remoteName, version, offset); len(ranges) > 1 {
+		err = c.getRanges(ctx, remoteName, file, ranges, whole)
+	} else {
+		err = c.get(remoteName, version, offset, file, h)
+	}
+	stop()
+	if err == nil {
+		// Make sure the data is on disk before it shows up under the real name
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) || errors.Is(err, ErrChecksum) {
+		// Nothing worth resuming
+		os.Remove(partial)
+	}
+	if err != nil {
+		return c.contextErr(ctx, err)
+	}
+
+	if err := os.Rename(partial, localPath); err != nil {
+		return err
+	}
+	return util.SyncDir(filepath.Dir(localPath))
+}
+
+// GetWriter downloads remoteName into w. The checksum is only known once all
+// of the data has been written, so w has already received the whole file
+// when ErrChecksum is returned.
+func (c *Client) GetWriter(ctx context.Context, remoteName string, w io.Writer) error {
+	h, _ := util.NewHash(c.hash)
+
+	defer c.watch(ctx)()
+	err := c.get(remoteName, 0, 0, w, h)
+	return c.contextErr(ctx, err)
+}
+
+// List returns every file on the server whose name starts with prefix, sorted
+// by name.
+func (c *Client) List(ctx context.Context, prefix string) ([]*messages.FileEntry, error) {
+	defer c.watch(ctx)()
+
+	var entries []*messages.FileEntry
+	pageToken := ""
+	for {
+		if err := c.msgHandler.SendListRequest(prefix, 0, pageToken); err != nil {
+			return nil, c.contextErr(ctx, err)
+		}
+		reply, err := c.receive("list", prefix)
+		if err != nil {
+			return nil, c.contextErr(ctx, err)
+		}
+		entries = append(entries, reply.GetListResp().GetEntries()...)
+
+		pageToken = reply.GetListResp().GetNextPageToken()
+		if pageToken == "" {
+			return entries, nil
+		}
 	}
+}
+
+// Delete removes remoteName from the server.
+func (c *Client) Delete(ctx context.Context, remoteName string) error {
+	defer c.watch(ctx)()
 
-	// Tell the server we want to store this file
-	msgHandler.SendStorageRequest(fileName, uint64(info.Size()))
-	if ok, _ := msgHandler.ReceiveResponse(); !ok {
-		return 1
+	if err := c.msgHandler.SendDeleteRequest(remoteName); err != nil {
+		return c.contextErr(ctx, err)
 	}
+	_, err := c.receive("delete", remoteName)
+	return c.contextErr(ctx, err)
+}
 
-	file, _ := os.Open(fileName)
-	md5 := md5.New()
-	w := io.MultiWriter(msgHandler, md5)
-	io.CopyN(w, file, info.Size()) // Checksum and transfer file at same time
-	file.Close()
+// hello tells the server which protocol versions and features the client
+// supports, and leaves out of transfers whatever the server doesn't.
+func (c *Client) hello(ctx context.Context) error {
+	defer c.watch(ctx)()
 
-	checksum := md5.Sum(nil)
-	msgHandler.SendChecksumVerification(checksum)
-	if ok, _ := msgHandler.ReceiveResponse(); !ok {
-		return 1
+	err := c.msgHandler.SendHello(&messages.Hello{
+		Version:      messages.ProtocolVersion,
+		MinVersion:   messages.MinProtocolVersion,
+		Hashes:       util.Hashes(),
+		Compressions: util.Compressions(),
+		Resume:       true,
+		Chunked:      true,
+		Ranges:       true,
+	})
+	if err != nil {
+		return c.contextErr(ctx, err)
+	}
+	reply, err := c.receive("hello", "")
+	if errors.Is(err, ErrUnexpectedReply) {
+		return errNoHello
+	}
+	if err != nil {
+		return c.contextErr(ctx, err)
 	}
 
-	fmt.Println("Storage complete!")
-	return 0
+	server := reply.GetHello()
+	if server.GetVersion() < messages.MinProtocolVersion {
+		return fmt.Errorf("hello: server speaks protocol version %d, but %d or later is needed: %w",
+			server.GetVersion(), messages.MinProtocolVersion, ErrUnsupported)
+	}
+	if !slices.Contains(server.GetHashes(), c.hash) {
+		return fmt.Errorf("hello: server can't checksum with %s: %w", util.HashAlgorithmName(c.hash), ErrUnsupported)
+	}
+	if !slices.Contains(server.GetCompressions(), c.compress) {
+		c.compress = messages.Compression_NONE
+	}
+	c.chunked = c.chunked && server.GetChunked()
+	c.ranges = server.GetRanges()
+	c.server = server
+	return nil
 }
 
-func get(msgHandler *messages.MessageHandler, fileName string) int {
-	fmt.Println("GET", fileName)
+func (c *Client) authenticate(ctx context.Context, token string) error {
+	defer c.watch(ctx)()
 
-	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err := c.msgHandler.SendAuthRequest(token); err != nil {
+		return c.contextErr(ctx, err)
+	}
+	reply, err := c.receive("auth", "")
 	if err != nil {
-		log.Println(err)
-		return 1
+		return c.contextErr(ctx, err)
 	}
 
-	msgHandler.SendRetrievalRequest(fileName)
-	ok, _, size := msgHandler.ReceiveRetrievalResponse()
-	if !ok {
-		return 1
+	c.user = reply.GetAuthResp().GetUser()
+	return nil
+}
+
+// put uploads size bytes from r. sum is the SHA-256 of all of them, or nil if
+// it isn't known.
+func (c *Client) put(r io.Reader, remoteName string, size int64, mode uint32, modTime int64, sum []byte) (*Stored, error) {
+	// Always ask to resume; the server starts from 0 if it has nothing, and
+	// skips to the end if it already has the content
+	request := &messages.StorageRequest{
+		FileName:    remoteName,
+		Size:        uint64(size),
+		Resume:      true,
+		Hash:        c.hash,
+		Mode:        mode,
+		ModTime:     modTime,
+		Conflict:    c.conflict,
+		Sha256:      sum,
+		Compression: c.compress,
+		Chunked:     c.chunked,
+	}
+	stored, err := c.sendPut(r, request)
+	if file, ok := r.(io.ReaderAt); ok && errors.Is(err, errStalePartial) {
+		// The server has thrown away what it held, so this starts from 0
+		return c.sendPut(io.NewSectionReader(file, 0, size), request)
 	}
+	return stored, err
+}
+
+// errStalePartial is returned by putData when the data the server resumed
+// from isn't the start of the file. The server discards it once the checksum
+// of the whole file doesn't match either.
+var errStalePartial = fmt.Errorf("the server resumed from data that isn't the start of the file and has thrown it away: %w", ErrChecksum)
 
-	md5 := md5.New()
-	w := io.MultiWriter(file, md5)
-	io.CopyN(w, msgHandler, int64(size))
-	file.Close()
+// sendPut sends a StorageRequest for the data in r and then the data.
+func (c *Client) sendPut(r io.Reader, request *messages.StorageRequest) (*Stored, error) {
+	if err := c.msgHandler.SendStorageRequest(request); err != nil {
+		return nil, err
+	}
+	reply, err := c.receive("put", request.GetFileName())
+	if err != nil {
+		return nil, err
+	}
+	return c.putData(r, request.GetFileName(), int64(request.GetSize()), reply)
+}
 
-	clientCheck := md5.Sum(nil)
-	checkMsg, _ := msgHandler.Receive()
-	serverCheck := checkMsg.GetChecksum().Checksum
+// putData sends the data of a file following the server's reply to its
+// StorageRequest, skipping what the server already holds, and returns where
+// the server stored it. The server is waiting for the data by then, so if r
+// can be read at any offset, the checksum of the whole file is worked out
+// alongside sending it; otherwise the part the server holds has to be read
+// and checksummed first.
+func (c *Client) putData(r io.Reader, remoteName string, size int64, reply *messages.Wrapper) (*Stored, error) {
+	offset := int64(reply.GetStorageResp().GetOffset())
+	if offset > size {
+		return nil, fmt.Errorf("put %s: server asked to resume at %d of %d bytes", remoteName, offset, size)
+	}
 
-	if util.VerifyChecksum(serverCheck, clientCheck) {
-		log.Println("Successfully retrieved file.")
+	h, _ := util.NewHash(c.hash)
+	var prefix []byte
+	var summed chan error
+	if file, ok := r.(io.ReaderAt); ok && offset > 0 {
+		summed = make(chan error, 1)
+		go func() {
+			_, err := io.Copy(h, io.NewSectionReader(file, 0, offset))
+			prefix = h.Sum(nil)
+			if err == nil {
+				_, err = io.Copy(h, io.NewSectionReader(file, offset, size-offset))
+			}
+			summed <- err
+		}()
+		r = io.NewSectionReader(file, offset, size-offset)
 	} else {
-		log.Println("FAILED to retrieve file. Invalid checksum.")
+		if _, err := io.CopyN(h, r, offset); err != nil {
+			return nil, err
+		}
+		prefix = h.Sum(nil)
+		r = io.TeeReader(r, h)
+	}
+
+	sr := reply.GetStorageResp()
+	if err := c.sendData(r, size-offset, sr.GetCompression(), sr.GetChunked()); err != nil {
+		return nil, c.dropped("put", remoteName, err)
+	}
+	if summed != nil {
+		if err := <-summed; err != nil {
+			return nil, err
+		}
+	}
+
+	// Older servers don't send the checksum of what they resumed from
+	stale := sr.GetPrefixChecksum() != nil && !bytes.Equal(prefix, sr.GetPrefixChecksum())
+	stored, err := c.finishPut(remoteName, h.Sum(nil))
+	if stale && errors.Is(err, ErrChecksum) {
+		return nil, fmt.Errorf("put %s: %w", remoteName, errStalePartial)
+	}
+	return stored, err
+}
+
+// finishPut sends the checksum of the whole file once its data has been sent,
+// and returns where the server stored it.
+func (c *Client) finishPut(remoteName string, checksum []byte) (*Stored, error) {
+	if err := c.msgHandler.SendChecksumVerification(c.hash, checksum); err != nil {
+		return nil, err
+	}
+	reply, err := c.receive("put", remoteName)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &Stored{Name: reply.GetStorageResp().GetFileName(), Version: reply.GetStorageResp().GetVersion()}
+	if stored.Name == "" {
+		// Older servers only say that it worked
+		stored.Name = remoteName
+	}
+	return stored, nil
+}
+
+// dropped returns the reason the server gave for hanging up in the middle of
+// sending it data, such as the transfer stalling, if it gave one, and err
+// otherwise.
+func (c *Client) dropped(op string, name string, err error) error {
+	if !errors.Is(err, syscall.EPIPE) && !errors.Is(err, syscall.ECONNRESET) {
+		return err
+	}
+	_, replyErr := c.receive(op, name)
+	var serverErr *ServerError
+	if errors.As(replyErr, &serverErr) {
+		return serverErr
 	}
+	return err
+}
 
-	return 0
+// get requests the given version of remoteName from offset onwards, writing
+// it to w. h must already hold the checksum of the first offset bytes.
+func (c *Client) get(remoteName string, version uint32, offset uint64, w io.Writer, h hash.Hash) error {
+	serverCheck, _, err := c.fetch(remoteName, version, offset, 0, false, io.MultiWriter(w, h))
+	if err != nil {
+		return err
+	}
+
+	clientCheck := &messages.ChecksumVerification{Algorithm: c.hash, Checksum: h.Sum(nil)}
+	if !util.VerifyChecksum(serverCheck, clientCheck) {
+		return checksumError(remoteName, serverCheck, clientCheck)
+	}
+	return nil
 }
 
-func main() {
-	if len(os.Args) < 4 {
-		fmt.Printf("Not enough arguments. Usage: %s server:port put|get file-name [download-dir]\n", os.Args[0])
-		os.Exit(1)
+// fetch requests length bytes (0 for the rest) of the given version of
+// remoteName from offset onwards, writing them to w, and returns the server's
+// checksum of the whole file. With rangeChecksum, it asks for the checksum of
+// just those bytes instead, and ofRange says whether the server sent that.
+func (c *Client) fetch(remoteName string, version uint32, offset uint64, length uint64, rangeChecksum bool, w io.Writer) (check *messages.ChecksumVerification, ofRange bool, err error) {
+	request := &messages.RetrievalRequest{
+		FileName:      remoteName,
+		Offset:        offset,
+		Length:        length,
+		Hash:          c.hash,
+		Version:       version,
+		Compression:   c.compress,
+		Chunked:       c.chunked,
+		RangeChecksum: rangeChecksum,
+	}
+	if err := c.msgHandler.SendRetrievalRequest(request); err != nil {
+		return nil, false, err
+	}
+	reply, err := c.receive("get", remoteName)
+	if err != nil {
+		return nil, false, err
 	}
 
-	host := os.Args[1]
-	conn, err := net.Dial("tcp", host)
+	rr := reply.GetRetrievalResp()
+	inSync, err := c.receiveData(w, int64(rr.GetSize()), rr.GetCompression(), rr.GetChunked())
 	if err != nil {
-		log.Fatalln(err.Error())
-		return
+		if !inSync {
+			return nil, false, err
+		}
+		var aborted *messages.AbortedError
+		if !errors.As(err, &aborted) {
+			// The server sends its checksum all the same
+			if _, err := c.msgHandler.Receive(); err != nil {
+				return nil, false, err
+			}
+		}
+		return nil, false, fmt.Errorf("get %s: %w", remoteName, err)
 	}
-	msgHandler := messages.NewMessageHandler(conn)
-	defer conn.Close()
 
-	action := strings.ToLower(os.Args[2])
-	if action != "put" && action != "get" {
-		log.Fatalln("Invalid action", action)
+	checkMsg, err := c.msgHandler.Receive()
+	if err != nil {
+		return nil, false, err
+	}
+	serverCheck := checkMsg.GetChecksum()
+	if serverCheck == nil {
+		return nil, false, fmt.Errorf("get %s: %w", remoteName, ErrUnexpectedReply)
+	}
+	if length > 0 && rr.GetSize() != length {
+		return nil, false, fmt.Errorf("get %s: got %d bytes of the %d asked for", remoteName, rr.GetSize(), length)
 	}
+	return serverCheck, rr.GetRangeChecksum(), nil
+}
 
-	fileName := os.Args[3]
+// sendData sends size bytes of file data from r, framed and compressed as
+// the server agreed to. If r fails partway through chunked data, the upload
+// is aborted, leaving the connection usable.
+func (c *Client) sendData(r io.Reader, size int64, compression messages.Compression, chunked bool) error {
+	w, err := c.msgHandler.NewDataWriter(compression, chunked)
+	if err != nil {
+		return err
+	}
 
-	dir := "."
-	if len(os.Args) >= 5 {
-		dir = os.Args[4]
+	n, err := w.CopyFrom(r, uint64(size))
+	var readErr *messages.ReadError
+	if chunked && errors.As(err, &readErr) {
+		// The server answers the abort, which says nothing new
+		if err := w.Abort(readErr.Error()); err != nil {
+			return err
+		}
+		if _, err := c.msgHandler.Receive(); err != nil {
+			return err
+		}
+		c.count(n, w.WireBytes())
+		return readErr.Err
+	}
+	if err == nil {
+		err = w.Close()
 	}
-	openDir, err := os.Open(dir)
+	c.count(n, w.WireBytes())
+	return err
+}
+
+// receiveData receives size bytes of file data into w, decompressing them if
+// the server sends them compressed. When it fails, inSync says whether the
+// rest of the data could be skipped, leaving the connection usable, which
+// chunked data allows.
+func (c *Client) receiveData(w io.Writer, size int64, compression messages.Compression, chunked bool) (inSync bool, err error) {
+	r, err := c.msgHandler.NewDataReader(compression, chunked, uint64(size))
 	if err != nil {
-		log.Fatalln(err)
+		return false, err
 	}
-	openDir.Close()
 
-	if action == "put" {
-		os.Exit(put(msgHandler, fileName))
-	} else if action == "get" {
-		os.Exit(get(msgHandler, fileName))
+	n, err := io.CopyN(w, r, size)
+	if err == io.EOF {
+		err = fmt.Errorf("data ended after %d of %d bytes", n, size)
+	}
+	closeErr := r.Close()
+	if err == nil {
+		err = closeErr
+	}
+	c.count(n, r.WireBytes())
+	if err != nil {
+		return chunked && closeErr == nil, err
+	}
+	return true, nil
+}
+
+func (c *Client) count(raw int64, wire int64) {
+	c.stats.Raw += raw
+	c.stats.Wire += wire
+}
+
+// receive reads the server's reply to a request, turning a refusal into a
+// *ServerError.
+func (c *Client) receive(op string, name string) (*messages.Wrapper, error) {
+	reply, err := c.msgHandler.Receive()
+	if err == io.EOF {
+		// The server hung up rather than reply
+		return nil, fmt.Errorf("%s %s: %w", op, name, ErrUnexpectedReply)
+	}
+	if c.legacy && errors.Is(err, messages.ErrTimeout) {
+		return nil, fmt.Errorf("%s %s: no reply, the server is too old to answer the request: %w", op, name, ErrUnsupported)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := messages.StatusOf(reply)
+	if status == nil {
+		return nil, fmt.Errorf("%s %s: %w", op, name, ErrUnexpectedReply)
+	}
+	if !status.GetOk() {
+		return nil, &ServerError{Op: op, Name: name, Message: status.GetMessage(), Code: status.GetCode(), Details: status.GetDetails()}
+	}
+	return reply, nil
+}
+
+// watch interrupts any blocked read or write on the connection if ctx is done
+// before the returned function is called.
+func (c *Client) watch(ctx context.Context) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.msgHandler.Interrupt()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// contextErr reports the context's error in place of the I/O error caused by
+// watch cutting the connection short.
+func (c *Client) contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
 	}
+	return err
 }
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"file-transfer/messages"
+	"file-transfer/util"
+)
+
+var (
+	// ErrExists is returned by Get when the local file is already there, and
+	// matches a refusal because the remote one is.
+	ErrExists = errors.New("file already exists")
+
+	// ErrNotRegular is returned by Put for directories and special files.
+	ErrNotRegular = errors.New("not a regular file")
+
+	// ErrChecksum is returned when a downloaded file doesn't match the
+	// checksum the server sent for it, and matches the server's refusal of an
+	// upload that doesn't match the client's.
+	ErrChecksum = errors.New("checksum mismatch")
+
+	// ErrUnexpectedReply is returned when the server answers with something
+	// other than a reply, usually because it dropped the connection.
+	ErrUnexpectedReply = errors.New("unexpected reply from server")
+
+	// ErrUnsupported is returned by Dial when the server can't speak a
+	// protocol version the client does, or use the checksum algorithm asked
+	// for, and matches the refusal of anything else the server doesn't
+	// support. Requests that a server from before the handshake leaves
+	// unanswered fail with it too.
+	ErrUnsupported = errors.New("not supported by the server")
+)
+
+// checksumError is the error for a download of remoteName that doesn't match
+// the server's checksum, showing both.
+func checksumError(remoteName string, serverCheck *messages.ChecksumVerification, clientCheck *messages.ChecksumVerification) error {
+	return fmt.Errorf("get %s: %w (server %s, client %s)", remoteName, ErrChecksum, util.FormatChecksum(serverCheck), util.FormatChecksum(clientCheck))
+}
+
+// A *ServerError matches the error that stands for its code, so errors.Is can
+// tell, e.g., a file that already exists on the server from an upload that
+// failed its checksum. Those that only come from the server are below.
+var (
+	ErrNotFound        = errors.New("file not found")
+	ErrPermission      = errors.New("permission denied")
+	ErrUnauthenticated = errors.New("authentication failed")
+	ErrQuota           = errors.New("quota exceeded")
+	ErrInvalidRequest  = errors.New("invalid request")
+	ErrAborted         = errors.New("transfer aborted")
+	ErrUnavailable     = errors.New("server unavailable")
+	ErrInternal        = errors.New("internal server error")
+)
+
+// codeErrors maps the codes of refusals to the errors they match.
+var codeErrors = map[messages.ErrorCode]error{
+	messages.ErrorCode_INVALID_REQUEST:   ErrInvalidRequest,
+	messages.ErrorCode_NOT_FOUND:         ErrNotFound,
+	messages.ErrorCode_ALREADY_EXISTS:    ErrExists,
+	messages.ErrorCode_PERMISSION_DENIED: ErrPermission,
+	messages.ErrorCode_UNAUTHENTICATED:   ErrUnauthenticated,
+	messages.ErrorCode_CHECKSUM_MISMATCH: ErrChecksum,
+	messages.ErrorCode_QUOTA_EXCEEDED:    ErrQuota,
+	messages.ErrorCode_UNSUPPORTED:       ErrUnsupported,
+	messages.ErrorCode_ABORTED:           ErrAborted,
+	messages.ErrorCode_UNAVAILABLE:       ErrUnavailable,
+	messages.ErrorCode_INTERNAL:          ErrInternal,
+}
+
+// ServerError is returned when the server refuses a request. Servers from
+// before error codes leave Code UNSPECIFIED, which matches none of the
+// errors above.
+type ServerError struct {
+	Op      string             // put, get, list, delete, auth or hello
+	Name    string             // The remote file name or prefix, if any
+	Message string             // The server's explanation
+	Code    messages.ErrorCode // Why it refused
+	Details map[string]string  // Such as "limit", for QUOTA_EXCEEDED over the size limit
+}
+
+func (e *ServerError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s: server refused: %s", e.Op, e.Message)
+	}
+	return fmt.Sprintf("%s %s: server refused: %s", e.Op, e.Name, e.Message)
+}
+
+// RetryAfter returns how long a busy server asked the client to wait before
+// connecting again, or 0 if it didn't.
+func (e *ServerError) RetryAfter() time.Duration {
+	seconds, err := strconv.Atoi(e.Details["retry_after"])
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Is reports whether target is the error e's code stands for. An upload the
+// server gave up on because the data stopped coming also matches
+// messages.ErrStalled, like a transfer the client gives up on itself.
+func (e *ServerError) Is(target error) bool {
+	if target == messages.ErrStalled && e.Details["stall_timeout"] != "" {
+		return true
+	}
+	err, ok := codeErrors[e.Code]
+	return ok && err == target
+}
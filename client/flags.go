@@ -0,0 +1,67 @@
+package client
+
+import (
+	"flag"
+	"os"
+
+	"file-transfer/util"
+)
+
+// Flags are the command line settings the client binaries share, from which
+// they build their Config.
+type Flags struct {
+	hash     string
+	tls      bool
+	caFile   string
+	certFile string
+	keyFile  string
+	conflict string
+	compress string
+	config   Config
+}
+
+// FlagsUsage lists the flags RegisterFlags defines, for usage messages.
+const FlagsUsage = "[-hash algorithm] [-tls] [-ca file] [-cert file -key file] [-token token] [-conflict policy] [-dedup] [-compress algorithm] [-chunked] [-streams n]"
+
+// RegisterFlags defines the shared flags on flags. Once it is parsed, Config
+// turns them into a Config.
+func RegisterFlags(flags *flag.FlagSet) *Flags {
+	f := &Flags{}
+	flags.StringVar(&f.hash, "hash", "md5", "checksum algorithm: md5, sha256, blake2b, xxhash or crc32c")
+	flags.BoolVar(&f.tls, "tls", false, "connect over TLS, verifying the server against the system roots or -ca")
+	flags.StringVar(&f.caFile, "ca", "", "CA certificate used to verify the server (implies -tls)")
+	flags.StringVar(&f.certFile, "cert", "", "client certificate for servers that require one (implies -tls)")
+	flags.StringVar(&f.keyFile, "key", "", "private key for -cert")
+	flags.StringVar(&f.conflict, "conflict", "reject", "what the server does when a put names a file it already has: reject, overwrite, keep-both or version")
+	flags.BoolVar(&f.config.Dedup, "dedup", false, "tell the server each file's SHA-256 before a put, so it can skip files it already has")
+	flags.StringVar(&f.compress, "compress", "none", "compress file data on the wire if the server supports it: none, gzip or zstd")
+	flags.BoolVar(&f.config.Chunked, "chunked", false, "send file data as checksummed chunks if the server supports it, so corruption is pinpointed and errors don't drop the connection")
+	flags.IntVar(&f.config.Streams, "streams", 1, "transfer files larger than a few MB over this many connections at once")
+	flags.StringVar(&f.config.Token, "token", os.Getenv("FILE_TRANSFER_TOKEN"), "token for servers that require authentication (default $FILE_TRANSFER_TOKEN)")
+	return f
+}
+
+// Config returns the Config the flags describe, loading the TLS certificates
+// they name.
+func (f *Flags) Config() (*Config, error) {
+	config := f.config
+
+	var err error
+	config.Hash, err = util.ParseHashAlgorithm(f.hash)
+	if err != nil {
+		return nil, err
+	}
+	config.TLS, err = util.ClientTLSConfig(f.tls, f.caFile, f.certFile, f.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config.Conflict, err = util.ParseConflictPolicy(f.conflict)
+	if err != nil {
+		return nil, err
+	}
+	config.Compression, err = util.ParseCompression(f.compress)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
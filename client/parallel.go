@@ -0,0 +1,294 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"file-transfer/messages"
+	"file-transfer/util"
+)
+
+// minRangeSize is the smallest range worth a connection of its own.
+const minRangeSize = 1 << 20
+
+// fileRange is the part of a file sent over one connection.
+type fileRange struct {
+	offset int64
+	length int64
+}
+
+// splitRanges divides a file of the given size into at most streams ranges of
+// about the same length, with fewer of them for smaller files.
+func splitRanges(size int64, streams int) []fileRange {
+	if n := size / minRangeSize; int64(streams) > n {
+		streams = int(n)
+	}
+	if streams < 1 {
+		streams = 1
+	}
+
+	ranges := make([]fileRange, streams)
+	length := (size + int64(streams) - 1) / int64(streams)
+	for i := range ranges {
+		ranges[i].offset = int64(i) * length
+		ranges[i].length = min(length, size-ranges[i].offset)
+	}
+	return ranges
+}
+
+// dialStream opens another connection to the same server with the same
+// settings, to transfer a range over.
+func (c *Client) dialStream(ctx context.Context) (*Client, error) {
+	config := *c.config
+	config.Streams = 0
+	return Dial(ctx, c.host, &config)
+}
+
+// eachRange runs transfer for every range at once, the first over c and the
+// others over connections of their own. The data those transfer is added to
+// c's Stats. Ranges that don't get a connection because the server is busy
+// are transferred over c after its own.
+func (c *Client) eachRange(ctx context.Context, ranges []fileRange, transfer func(s *Client, i int) error) error {
+	errs := make([]error, len(ranges))
+	streams := make([]*Client, len(ranges))
+	busy := make([]bool, len(ranges))
+	var wg sync.WaitGroup
+	for i := 1; i < len(ranges); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s, err := c.dialStream(ctx)
+			if errors.Is(err, ErrUnavailable) {
+				busy[i] = true
+				return
+			}
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer s.Close()
+			streams[i] = s
+
+			defer s.watch(ctx)()
+			errs[i] = transfer(s, i)
+		}(i)
+	}
+	errs[0] = transfer(c, 0)
+	wg.Wait()
+	for i := range ranges {
+		if busy[i] && errors.Join(errs...) == nil {
+			errs[i] = transfer(c, i)
+		}
+	}
+
+	for _, s := range streams[1:] {
+		if s != nil {
+			c.count(s.stats.Raw, s.stats.Wire)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// putRanges uploads a file in ranges over several connections, then has the
+// server put them together and check the checksum of the whole file. If the
+// server can't, the file is uploaded over c alone.
+func (c *Client) putRanges(ctx context.Context, file *os.File, remoteName string, size int64, mode uint32, modTime int64, ranges []fileRange) (*Stored, error) {
+	// The reply to the first range tells whether the server takes ranges
+	first := ranges[0]
+	if err := c.msgHandler.SendStorageRequest(c.rangeRequest(remoteName, size, first)); err != nil {
+		return nil, err
+	}
+	reply, err := c.receive("put", remoteName)
+	if errors.Is(err, ErrUnsupported) {
+		// Its storage can't put ranges together
+		return c.put(file, remoteName, size, mode, modTime, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if reply.GetStorageResp().GetRangeLength() != uint64(first.length) {
+		// Older servers take it for the whole file
+		return c.putData(file, remoteName, size, reply)
+	}
+
+	err = c.eachRange(ctx, ranges, func(s *Client, i int) error {
+		r, rangeReply := ranges[i], reply
+		if i > 0 {
+			if err := s.msgHandler.SendStorageRequest(s.rangeRequest(remoteName, size, r)); err != nil {
+				return err
+			}
+			var err error
+			if rangeReply, err = s.receive("put", remoteName); err != nil {
+				return err
+			}
+		}
+		return s.putRange(io.NewSectionReader(file, r.offset, r.length), remoteName, r.length, rangeReply)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Checksumming the whole file takes reading all of it again, which is
+	// done before asking, as the server waits for the checksum once it has
+	// put the ranges together
+	h, _ := util.NewHash(c.hash)
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, size)); err != nil {
+		return nil, err
+	}
+	request := &messages.StorageRequest{
+		FileName: remoteName,
+		Size:     uint64(size),
+		Resume:   true,
+		Hash:     c.hash,
+		Mode:     mode,
+		ModTime:  modTime,
+		Conflict: c.conflict,
+		Assemble: true,
+	}
+	if err := c.msgHandler.SendStorageRequest(request); err != nil {
+		return nil, err
+	}
+	reply, err = c.receive("put", remoteName)
+	if err != nil {
+		return nil, err
+	}
+	sr := reply.GetStorageResp()
+	if sr.GetOffset() != uint64(size) {
+		return nil, fmt.Errorf("put %s: server asked for data at %d after putting the ranges together: %w", remoteName, sr.GetOffset(), ErrUnexpectedReply)
+	}
+	if err := c.sendData(bytes.NewReader(nil), 0, sr.GetCompression(), sr.GetChunked()); err != nil {
+		return nil, err
+	}
+	return c.finishPut(remoteName, h.Sum(nil))
+}
+
+// rangeRequest asks to upload range r of remoteName, a file of the given
+// size. The server checks the conflict policy before taking any of the data.
+func (c *Client) rangeRequest(remoteName string, size int64, r fileRange) *messages.StorageRequest {
+	return &messages.StorageRequest{
+		FileName:    remoteName,
+		Size:        uint64(size),
+		Resume:      true,
+		Hash:        c.hash,
+		Conflict:    c.conflict,
+		Compression: c.compress,
+		Chunked:     c.chunked,
+		RangeOffset: uint64(r.offset),
+		RangeLength: uint64(r.length),
+	}
+}
+
+// putRange sends the data of a range following the server's reply to its
+// request, along with its checksum.
+func (c *Client) putRange(r io.Reader, remoteName string, length int64, reply *messages.Wrapper) error {
+	h, _ := util.NewHash(c.hash)
+	sr := reply.GetStorageResp()
+	if err := c.sendData(io.TeeReader(r, h), length, sr.GetCompression(), sr.GetChunked()); err != nil {
+		return c.dropped("put", remoteName, err)
+	}
+
+	if err := c.msgHandler.SendChecksumVerification(c.hash, h.Sum(nil)); err != nil {
+		return err
+	}
+	_, err := c.receive("put", remoteName)
+	return err
+}
+
+// downloadRanges decides how to split the download of remoteName, which is
+// only done for the current version of a large file that isn't being resumed.
+// It also returns the checksum of the whole file the server listed, if it
+// has one of the client's algorithm. Any trouble finding out the size is left
+// for the plain download to report.
+func (c *Client) downloadRanges(remoteName string, version uint32, offset uint64) ([]fileRange, *messages.ChecksumVerification) {
+	if c.config.Streams <= 1 || version != 0 || offset != 0 {
+		return nil, nil
+	}
+
+	// The file itself sorts first among those its name is a prefix of
+	if err := c.msgHandler.SendListRequest(remoteName, 1, ""); err != nil {
+		return nil, nil
+	}
+	reply, err := c.receive("get", remoteName)
+	if err != nil {
+		return nil, nil
+	}
+	entries := reply.GetListResp().GetEntries()
+	if len(entries) == 0 || entries[0].GetName() != remoteName {
+		return nil, nil
+	}
+
+	entry := entries[0]
+	var whole *messages.ChecksumVerification
+	if len(entry.GetChecksum()) > 0 && entry.GetChecksumAlgorithm() == c.hash {
+		whole = &messages.ChecksumVerification{Algorithm: entry.GetChecksumAlgorithm(), Checksum: entry.GetChecksum()}
+	}
+	return splitRanges(int64(entry.GetSize()), c.config.Streams), whole
+}
+
+// getRanges downloads the current version of remoteName into file in ranges
+// over several connections. Each range is checked against the server's
+// checksum of just that range as it arrives, and the assembled file against
+// the checksum of the whole file: whole, from the listing, or else the one
+// the first range is retrieved with. Servers that don't checksum ranges send
+// that with every range. If it fails, the file is emptied, as the ranges that
+// did arrive can't be resumed.
+func (c *Client) getRanges(ctx context.Context, remoteName string, file *os.File, ranges []fileRange, whole *messages.ChecksumVerification) error {
+	checks := make([]*messages.ChecksumVerification, len(ranges)) // Of the whole file
+	err := c.eachRange(ctx, ranges, func(s *Client, i int) error {
+		r := ranges[i]
+		h, _ := util.NewHash(s.hash)
+		check, ofRange, err := s.fetch(remoteName, 0, uint64(r.offset), uint64(r.length), whole != nil || i > 0, io.MultiWriter(io.NewOffsetWriter(file, r.offset), h))
+		if err != nil || !ofRange {
+			checks[i] = check
+			return err
+		}
+
+		clientCheck := &messages.ChecksumVerification{Algorithm: s.hash, Checksum: h.Sum(nil)}
+		if !util.VerifyChecksum(check, clientCheck) {
+			return checksumError(fmt.Sprintf("%s (bytes %d-%d)", remoteName, r.offset, r.offset+r.length), check, clientCheck)
+		}
+		return nil
+	})
+	if err == nil {
+		last := ranges[len(ranges)-1]
+		err = c.verifyRanges(remoteName, io.NewSectionReader(file, 0, last.offset+last.length), append(checks, whole))
+	}
+	if err != nil {
+		file.Truncate(0)
+	}
+	return err
+}
+
+// verifyRanges checks that the checksums of the whole file the ranges came
+// with, nil for those that came without one, are all the same, and that data
+// holds all of the file.
+func (c *Client) verifyRanges(remoteName string, data io.Reader, checks []*messages.ChecksumVerification) error {
+	var whole *messages.ChecksumVerification
+	for _, check := range checks {
+		switch {
+		case check == nil:
+		case whole == nil:
+			whole = check
+		case check.GetAlgorithm() != whole.GetAlgorithm() || !bytes.Equal(check.GetChecksum(), whole.GetChecksum()):
+			return fmt.Errorf("get %s: file changed during the download: %w", remoteName, ErrChecksum)
+		}
+	}
+	if whole == nil {
+		return fmt.Errorf("get %s: no checksum of the whole file: %w", remoteName, ErrUnexpectedReply)
+	}
+
+	h, _ := util.NewHash(c.hash)
+	if _, err := io.Copy(h, data); err != nil {
+		return err
+	}
+	clientCheck := &messages.ChecksumVerification{Algorithm: c.hash, Checksum: h.Sum(nil)}
+	if !util.VerifyChecksum(whole, clientCheck) {
+		return checksumError(remoteName, whole, clientCheck)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"file-transfer/storage"
+	"file-transfer/util"
+)
+
+// PutTree uploads every file under localDir over this connection. Files are
+// named by their path relative to localDir's parent, so the tree keeps its
+// top-level name on the server. It carries on past files that fail and
+// records them in the returned summary, along with the hidden files and
+// special files it skipped; the error is only set if the tree couldn't be
+// read at all.
+func (c *Client) PutTree(ctx context.Context, localDir string) (*util.Summary, error) {
+	files, skipped, err := util.WalkTree(localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &util.Summary{}
+	for _, entry := range skipped {
+		summary.Skip(entry)
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		_, err := c.Put(ctx, file.Path, file.Name)
+		summary.Add(file.Name, err)
+	}
+	return summary, nil
+}
+
+// GetTree downloads every file the server lists under remoteDir into localDir,
+// recreating the tree there with the files' permissions and modification
+// times. Like PutTree it carries on past files that fail.
+func (c *Client) GetTree(ctx context.Context, remoteDir string, localDir string) (*util.Summary, error) {
+	entries, err := c.List(ctx, strings.TrimSuffix(remoteDir, "/")+"/")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("get %s: no files found", remoteDir)
+	}
+
+	// Names come from the server, so don't let them lead outside of localDir
+	local, err := storage.NewRoot(localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &util.Summary{}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		localPath, err := local.Resolve(entry.GetName())
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(localPath), 0777)
+		}
+		if err == nil {
+			err = c.Get(ctx, entry.GetName(), localPath)
+		}
+		if err == nil {
+			err = util.ApplyMetadata(localPath, entry.GetMode(), entry.GetModTime())
+		}
+		summary.Add(entry.GetName(), err)
+	}
+	return summary, nil
+}
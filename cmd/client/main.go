@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"file-transfer/client"
+	"file-transfer/messages"
+	"file-transfer/util"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func put(c *client.Client, fileName string) int {
+	fmt.Println("PUT", fileName)
+
+	stored, err := c.Put(context.Background(), fileName, filepath.Base(fileName))
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	fmt.Println("Storage complete! Stored as", stored)
+	return 0
+}
+
+func get(c *client.Client, fileName string, version uint32, dir string) int {
+	fmt.Println("GET", fileName)
+
+	if err := c.GetVersion(context.Background(), fileName, version, filepath.Join(dir, fileName)); err != nil {
+		log.Println("FAILED to retrieve file.", err)
+		return 1
+	}
+
+	log.Println("Successfully retrieved file.")
+	return 0
+}
+
+func putTree(c *client.Client, dir string) int {
+	fmt.Println("PUT", dir)
+
+	summary, err := c.PutTree(context.Background(), dir)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	fmt.Println(summary)
+	if summary.Failed() > 0 {
+		return 1
+	}
+	return 0
+}
+
+func getTree(c *client.Client, remoteDir string, dir string) int {
+	fmt.Println("GET", remoteDir)
+
+	summary, err := c.GetTree(context.Background(), remoteDir, dir)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	fmt.Println(summary)
+	if summary.Failed() > 0 {
+		return 1
+	}
+	return 0
+}
+
+func list(c *client.Client, prefix string) int {
+	fmt.Println("LIST", prefix)
+
+	entries, err := c.List(context.Background(), prefix)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	for _, entry := range entries {
+		fmt.Println(util.FormatFileEntry(entry))
+	}
+	return 0
+}
+
+func del(c *client.Client, fileName string) int {
+	fmt.Println("DELETE", fileName)
+
+	if err := c.Delete(context.Background(), fileName); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	fmt.Println("Delete complete!")
+	return 0
+}
+
+func main() {
+	flags := client.RegisterFlags(flag.CommandLine)
+	version := flag.Uint("version", 0, "get this older version of the file rather than the current one")
+	recursive := flag.Bool("r", false, "put or get a whole directory tree")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 2 {
+		fmt.Printf("Not enough arguments. Usage: %s [-r] %s [-version n] server:port put|get|delete|list [file-name|prefix] [download-dir]\n", os.Args[0], client.FlagsUsage)
+		os.Exit(1)
+	}
+
+	config, err := flags.Config()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	action := strings.ToLower(args[1])
+	if action != "put" && action != "get" && action != "delete" && action != "list" {
+		log.Fatalln("Invalid action", action)
+	}
+
+	fileName := ""
+	if len(args) >= 3 {
+		fileName = args[2]
+	} else if action != "list" {
+		log.Fatalln("Missing file name for", action)
+	}
+
+	dir := "."
+	if len(args) >= 4 {
+		dir = args[3]
+	}
+	openDir, err := os.Open(dir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	openDir.Close()
+
+	host := args[0]
+	c, err := client.Dial(context.Background(), host, config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer c.Close()
+
+	code := 0
+	if action == "put" && *recursive {
+		code = putTree(c, fileName)
+	} else if action == "put" {
+		code = put(c, fileName)
+	} else if action == "get" && *recursive {
+		code = getTree(c, fileName, dir)
+	} else if action == "get" {
+		code = get(c, fileName, uint32(*version), dir)
+	} else if action == "delete" {
+		code = del(c, fileName)
+	} else if action == "list" {
+		code = list(c, fileName)
+	}
+
+	if config.Compression != messages.Compression_NONE && c.Stats().Raw > 0 {
+		fmt.Println(c.Stats())
+	}
+	os.Exit(code)
+}
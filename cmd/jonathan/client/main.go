@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"file-transfer/client"
+	"file-transfer/messages"
+	"file-transfer/util"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// Set from the command line flags
+var config *client.Config
+
+// logStats shows how well the data compressed, if it was.
+func logStats(c *client.Client) {
+	if config.Compression != messages.Compression_NONE && c.Stats().Raw > 0 {
+		log.Println(c.Stats())
+	}
+}
+
+func put(url, filePath string) (bool, string) {
+	c, err := client.Dial(context.Background(), url, config)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer c.Close()
+
+	stored, err := c.Put(context.Background(), filePath, filepath.Base(filePath))
+	if err != nil {
+		return false, err.Error()
+	}
+
+	log.Println("Stored as", stored)
+	logStats(c)
+	return true, ""
+}
+
+// putTree uploads every file under dirPath over a single connection.
+func putTree(url, dirPath string) (bool, string) {
+	c, err := client.Dial(context.Background(), url, config)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer c.Close()
+
+	summary, err := c.PutTree(context.Background(), dirPath)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	log.Println(summary)
+	logStats(c)
+	if summary.Failed() > 0 {
+		return false, "Some files failed to upload"
+	}
+	return true, ""
+}
+
+func get(url, filePath string, version uint32, destinationDir string) (bool, string) {
+	c, err := client.Dial(context.Background(), url, config)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer c.Close()
+
+	if err := c.GetVersion(context.Background(), filePath, version, filepath.Join(destinationDir, filePath)); err != nil {
+		return false, err.Error()
+	}
+
+	logStats(c)
+	return true, ""
+}
+
+// getTree downloads every file the server lists under dirPath into
+// destinationDir over a single connection, keeping their permissions and
+// mtimes.
+func getTree(url, dirPath, destinationDir string) (bool, string) {
+	c, err := client.Dial(context.Background(), url, config)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer c.Close()
+
+	summary, err := c.GetTree(context.Background(), dirPath, destinationDir)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	log.Println(summary)
+	logStats(c)
+	if summary.Failed() > 0 {
+		return false, "Some files failed to download"
+	}
+	return true, ""
+}
+
+func del(url, filePath string) (bool, string) {
+	c, err := client.Dial(context.Background(), url, config)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer c.Close()
+
+	if err := c.Delete(context.Background(), filePath); err != nil {
+		return false, err.Error()
+	}
+
+	return true, ""
+}
+
+func list(url, prefix string) (bool, string) {
+	c, err := client.Dial(context.Background(), url, config)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer c.Close()
+
+	entries, err := c.List(context.Background(), prefix)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	for _, entry := range entries {
+		fmt.Println(util.FormatFileEntry(entry))
+	}
+	return true, ""
+}
+
+func main() {
+	flags := client.RegisterFlags(flag.CommandLine)
+	version := flag.Uint("version", 0, "get this older version of the file rather than the current one")
+	recursive := flag.Bool("r", false, "put or get a whole directory tree")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 2 {
+		log.Fatalf("Usage: ./client [-r] %s [-version n] host:port action [file-name|prefix] [destination-dir]\n", client.FlagsUsage)
+	}
+
+	var err error
+	config, err = flags.Config()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	url := args[0]
+	action := args[1]
+	filePath := ""
+	if len(args) >= 3 {
+		filePath = args[2]
+	} else if action != "list" {
+		log.Fatalln("Usage: ./client host:port action file-name [destination-dir]")
+	}
+
+	if action == "put" && *recursive {
+		if ok, err := putTree(url, filePath); !ok {
+			log.Fatalln("Error to put directory", err)
+		}
+	} else if action == "put" {
+		if ok, err := put(url, filePath); !ok {
+			log.Fatalln("Error to put file", err)
+		}
+	} else if action == "get" {
+		destinationDir := "."
+		if len(args) == 4 {
+			destinationDir = args[3]
+		}
+
+		if *recursive {
+			if ok, err := getTree(url, filePath, destinationDir); !ok {
+				log.Fatalln("Error to get directory", err)
+			}
+		} else if ok, err := get(url, filePath, uint32(*version), destinationDir); !ok {
+			log.Fatalln("Error to get file", err)
+		}
+	} else if action == "delete" {
+		if ok, err := del(url, filePath); !ok {
+			log.Fatalln("Error to delete file", err)
+		}
+	} else if action == "list" {
+		if ok, err := list(url, filePath); !ok {
+			log.Fatalln("Error to list files", err)
+		}
+	}
+}
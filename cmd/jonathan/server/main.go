@@ -0,0 +1,57 @@
+package main
+
+import (
+	"file-transfer/server"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	flags := server.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		fmt.Printf("Not enough arguments. Usage: %s %s port [download-dir|s3://bucket[/prefix]]\n", os.Args[0], server.FlagsUsage)
+		os.Exit(1)
+	}
+
+	dir := "."
+	if len(args) >= 2 {
+		dir = args[1]
+	}
+	srv, err := flags.NewServer(dir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	port := args[0]
+	listener, err := flags.Listen(port)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Println("Listening on port:", port)
+	fmt.Println("Download directory:", dir)
+	if tls, clientCerts := flags.TLS(); tls {
+		fmt.Println("TLS enabled, client certificates required:", clientCerts)
+	}
+	if srv.Credentials != nil {
+		fmt.Println("Token authentication required")
+	}
+	if flags.Dedup {
+		fmt.Println("Deduplicating identical files")
+	}
+	if srv.ACL != nil {
+		fmt.Println("Access control enabled")
+	}
+
+	err = flags.ServeUntilSignal(srv, listener, func(sig os.Signal) {
+		fmt.Println("Received", sig.String()+", shutting down")
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"file-transfer/client"
+	"file-transfer/messages"
+	"file-transfer/util"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func put(ctx context.Context, c *client.Client, fileName string) error {
+	fmt.Println("PUT", fileName)
+
+	stored, err := c.Put(ctx, fileName, filepath.Base(fileName))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Storage complete! Stored as", stored)
+	return nil
+}
+
+func get(ctx context.Context, c *client.Client, fileName string, version uint32) error {
+	fmt.Println("GET", fileName)
+
+	if err := c.GetVersion(ctx, fileName, version, fileName); err != nil {
+		return err
+	}
+
+	log.Println("Successfully retrieved file.")
+	return nil
+}
+
+// putTree uploads every file under dir over the one connection, carrying on
+// past failures so one bad file doesn't stop the rest.
+func putTree(ctx context.Context, c *client.Client, dir string) error {
+	fmt.Println("PUT", dir)
+
+	summary, err := c.PutTree(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(summary)
+	if summary.Failed() > 0 {
+		return fmt.Errorf("%d files failed to upload", summary.Failed())
+	}
+	return nil
+}
+
+// getTree downloads every file the server lists under dir, recreating the
+// tree in the current directory with the files' permissions and mtimes.
+func getTree(ctx context.Context, c *client.Client, dir string) error {
+	fmt.Println("GET", dir)
+
+	summary, err := c.GetTree(ctx, dir, ".")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(summary)
+	if summary.Failed() > 0 {
+		return fmt.Errorf("%d files failed to download", summary.Failed())
+	}
+	return nil
+}
+
+func list(ctx context.Context, c *client.Client, prefix string) error {
+	fmt.Println("LIST", prefix)
+
+	entries, err := c.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		fmt.Println(util.FormatFileEntry(entry))
+	}
+	return nil
+}
+
+func del(ctx context.Context, c *client.Client, fileName string) error {
+	fmt.Println("DELETE", fileName)
+
+	if err := c.Delete(ctx, fileName); err != nil {
+		return err
+	}
+
+	fmt.Println("Delete complete!")
+	return nil
+}
+
+func main() {
+	flags := client.RegisterFlags(flag.CommandLine)
+	version := flag.Uint("version", 0, "get this older version of the file rather than the current one")
+	recursive := flag.Bool("r", false, "put or get a whole directory tree")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 2 {
+		fmt.Printf("Not enough arguments. Usage: %s [-r] %s [-version n] server:port put|get|delete|list [file-name|prefix] [download-dir]\n", os.Args[0], client.FlagsUsage)
+		os.Exit(1)
+	}
+
+	host := args[0]
+	action := strings.ToLower(args[1])
+	fileName := ""
+	if len(args) >= 3 {
+		fileName = args[2]
+	}
+
+	if action != "put" && action != "get" && action != "delete" && action != "list" {
+		log.Fatalln("Invalid action", action)
+	}
+	if fileName == "" && action != "list" {
+		log.Fatalln("Missing file name for", action)
+	}
+
+	config, err := flags.Config()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	dir := "."
+	if len(args) >= 4 {
+		dir = args[3]
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, host, config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer c.Close()
+
+	if c.User() != "" {
+		log.Println("Authenticated as", c.User())
+	}
+
+	switch {
+	case action == "put" && *recursive:
+		err = putTree(ctx, c, fileName)
+	case action == "put":
+		err = put(ctx, c, fileName)
+	case action == "get" && *recursive:
+		err = getTree(ctx, c, fileName)
+	case action == "get":
+		err = get(ctx, c, fileName, uint32(*version))
+	case action == "delete":
+		err = del(ctx, c, fileName)
+	case action == "list":
+		err = list(ctx, c, fileName)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if config.Compression != messages.Compression_NONE && c.Stats().Raw > 0 {
+		log.Println(c.Stats())
+	}
+}
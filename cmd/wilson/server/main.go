@@ -0,0 +1,55 @@
+package main
+
+import (
+	"file-transfer/server"
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	flags := server.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		log.Fatalf("Usage: %s %s port [download-dir|s3://bucket[/prefix]]\n", os.Args[0], server.FlagsUsage)
+	}
+
+	dir := "."
+	if len(args) >= 2 {
+		dir = args[1]
+	}
+	srv, err := flags.NewServer(dir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	port := args[0]
+	listener, err := flags.Listen(port)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Println("Listening on port:", port)
+	log.Println("Download directory:", dir)
+	if tls, clientCerts := flags.TLS(); tls {
+		log.Println("TLS enabled, client certificates required:", clientCerts)
+	}
+	if srv.Credentials != nil {
+		log.Println("Token authentication required")
+	}
+	if flags.Dedup {
+		log.Println("Deduplicating identical files")
+	}
+	if srv.ACL != nil {
+		log.Println("Access control enabled")
+	}
+
+	err = flags.ServeUntilSignal(srv, listener, func(sig os.Signal) {
+		log.Println("Received", sig.String()+", shutting down")
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,171 @@
+package messages
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxChunkSize is the most file data a DataChunk carries.
+const MaxChunkSize = 64 * 1024
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ChunkError reports a chunk whose data doesn't match its checksum. The
+// offset and length are in the data as sent, i.e. compressed if it is.
+type ChunkError struct {
+	Sequence uint64
+	Offset   uint64
+	Length   int
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (bytes %d-%d) is corrupted", e.Sequence, e.Offset, e.Offset+uint64(e.Length))
+}
+
+// AbortedError reports that the peer gave up on sending chunked data, and why.
+type AbortedError struct {
+	Message string
+}
+
+func (e *AbortedError) Error() string {
+	return "transfer aborted by peer: " + e.Message
+}
+
+// chunkWriter sends data as DataChunk messages.
+type chunkWriter struct {
+	m        *MessageHandler
+	sequence uint64
+	done     bool
+	n        int64
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, fmt.Errorf("write after end of data")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		data := p
+		if len(data) > MaxChunkSize {
+			data = data[:MaxChunkSize]
+		}
+		if err := w.chunk(data, false); err != nil {
+			return written, err
+		}
+		written += len(data)
+		p = p[len(data):]
+	}
+	return written, nil
+}
+
+// end sends the empty last chunk.
+func (w *chunkWriter) end() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	return w.chunk(nil, true)
+}
+
+// abort sends a failed Response in place of the next chunk.
+func (w *chunkWriter) abort(reason string) error {
+	if w.done {
+		return fmt.Errorf("data has been sent already")
+	}
+	w.done = true
+	return w.send(&Wrapper{
+		Msg: &Wrapper_Response{Response: Refusal(ErrorCode_ABORTED, reason)},
+	})
+}
+
+func (w *chunkWriter) chunk(data []byte, last bool) error {
+	msg := DataChunk{Sequence: w.sequence, Data: data, Crc32C: crc32.Checksum(data, castagnoli), Last: last}
+	w.sequence++
+	return w.send(&Wrapper{
+		Msg: &Wrapper_DataChunk{DataChunk: &msg},
+	})
+}
+
+func (w *chunkWriter) send(wrapper *Wrapper) error {
+	if err := w.m.send(wrapper, w.m.timeouts.Stall, ErrStalled, "peer took no data for"); err != nil {
+		return err
+	}
+	w.n += int64(proto.Size(wrapper) + 8)
+	return nil
+}
+
+func (w *chunkWriter) wireBytes() int64 {
+	return w.n
+}
+
+// chunkReader reads the data of DataChunk messages up to the last one. A
+// corrupted chunk fails the Read that gets to it, but the ones after it can
+// still be read.
+type chunkReader struct {
+	m        *MessageHandler
+	sequence uint64 // Of the next chunk
+	offset   uint64 // Of the next chunk's data
+	data     []byte // What is left of the current chunk
+	err      error  // What Read fails with once the data is over
+	n        int64
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// next receives the next chunk.
+func (r *chunkReader) next() error {
+	wrapper, err := r.m.receive(r.m.timeouts.Stall, ErrStalled, "no data for")
+	if err != nil {
+		r.err = unexpectedEOF(err)
+		return r.err
+	}
+	r.n += int64(proto.Size(wrapper) + 8)
+
+	switch msg := wrapper.Msg.(type) {
+	case *Wrapper_DataChunk:
+		chunk := msg.DataChunk
+		if chunk.GetSequence() != r.sequence {
+			r.err = fmt.Errorf("expected chunk %d, got chunk %d", r.sequence, chunk.GetSequence())
+			return r.err
+		}
+		r.sequence++
+		offset := r.offset
+		r.offset += uint64(len(chunk.GetData()))
+		if chunk.GetLast() {
+			r.err = io.EOF
+		}
+
+		if crc32.Checksum(chunk.GetData(), castagnoli) != chunk.GetCrc32C() {
+			return &ChunkError{Sequence: chunk.GetSequence(), Offset: offset, Length: len(chunk.GetData())}
+		}
+		r.data = chunk.GetData()
+		return nil
+	case *Wrapper_Response:
+		r.err = &AbortedError{Message: msg.Response.GetMessage()}
+		return r.err
+	default:
+		r.err = fmt.Errorf("expected chunk %d, got %T", r.sequence, wrapper.Msg)
+		return r.err
+	}
+}
+
+func (r *chunkReader) wireBytes() int64 {
+	return r.n
+}
@@ -0,0 +1,351 @@
+package messages
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// File data follows a StorageResponse or RetrievalResponse in one of three
+// framings. Plain data is spliced into the connection as is, since its size
+// is known up front. Compressed data, whose size isn't, is sent in blocks: an
+// 8-byte little-endian length followed by that many bytes, with an empty
+// block at the end. Chunked data, compressed or not, travels as DataChunk
+// messages.
+
+// DataWriter sends file data to the peer, compressed and framed as agreed.
+type DataWriter struct {
+	w      io.Writer      // What Write writes to
+	enc    io.WriteCloser // nil without compression
+	frames frameWriter
+}
+
+// frameWriter carries the (compressed) data over the connection.
+type frameWriter interface {
+	io.Writer
+	end() error       // Marks the end of the data
+	wireBytes() int64 // Bytes sent over the connection so far
+}
+
+// NewDataWriter starts sending file data. Close must be called to finish it
+// before anything else is sent.
+func (m *MessageHandler) NewDataWriter(compression Compression, chunked bool) (*DataWriter, error) {
+	d := &DataWriter{}
+	switch {
+	case chunked:
+		d.frames = &chunkWriter{m: m}
+	case compression != Compression_NONE:
+		d.frames = &blockWriter{m: m}
+	default:
+		d.frames = &rawWriter{m: m}
+	}
+
+	var err error
+	switch compression {
+	case Compression_NONE:
+	case Compression_GZIP:
+		d.enc = gzip.NewWriter(d.frames)
+	case Compression_ZSTD:
+		d.enc, err = zstd.NewWriter(d.frames, zstd.WithEncoderConcurrency(1))
+	default:
+		err = fmt.Errorf("unsupported compression %v", compression)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.w = d.frames
+	if d.enc != nil {
+		d.w = d.enc
+	}
+	return d, nil
+}
+
+func (d *DataWriter) Write(p []byte) (int, error) {
+	return d.w.Write(p)
+}
+
+// CopyFrom sends size bytes read from r, returning how many it sent. A
+// failure to read them is returned as a *ReadError, after which chunked data
+// can still be aborted.
+func (d *DataWriter) CopyFrom(r io.Reader, size uint64) (int64, error) {
+	buf := make([]byte, MaxChunkSize)
+	sent := int64(0)
+	for uint64(sent) < size {
+		n := uint64(len(buf))
+		if left := size - uint64(sent); n > left {
+			n = left
+		}
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return sent, &ReadError{Err: unexpectedEOF(err)}
+		}
+		if _, err := d.Write(buf[:n]); err != nil {
+			return sent, err
+		}
+		sent += int64(n)
+	}
+	return sent, nil
+}
+
+// Close flushes what is left of the compressed data and ends the stream. It
+// doesn't close the connection.
+func (d *DataWriter) Close() error {
+	if d.enc != nil {
+		if err := d.enc.Close(); err != nil {
+			return err
+		}
+	}
+	return d.frames.end()
+}
+
+// Abort gives up on sending the data, telling the peer why. Only chunked data
+// can be given up on without closing the connection; otherwise Abort returns
+// an error and does nothing.
+func (d *DataWriter) Abort(reason string) error {
+	chunks, ok := d.frames.(*chunkWriter)
+	if !ok {
+		return fmt.Errorf("can't abort unchunked data")
+	}
+	return chunks.abort(reason)
+}
+
+// WireBytes returns the number of bytes sent over the connection so far.
+func (d *DataWriter) WireBytes() int64 {
+	return d.frames.wireBytes()
+}
+
+// ReadError reports that the data to be sent couldn't be read, as opposed
+// to sent.
+type ReadError struct {
+	Err error
+}
+
+func (e *ReadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// DataReader receives file data sent with a DataWriter.
+type DataReader struct {
+	r      io.Reader // What Read reads from
+	free   func()    // Releases the decoder's resources
+	frames frameReader
+}
+
+// frameReader takes the (compressed) data off the connection.
+type frameReader interface {
+	io.Reader
+	wireBytes() int64
+}
+
+// NewDataReader starts receiving size bytes of file data. Close must be
+// called to skip to the end of it before anything else is received.
+func (m *MessageHandler) NewDataReader(compression Compression, chunked bool, size uint64) (*DataReader, error) {
+	d := &DataReader{free: func() {}}
+	switch {
+	case chunked:
+		d.frames = &chunkReader{m: m}
+	case compression != Compression_NONE:
+		d.frames = &blockReader{m: m}
+	default:
+		d.frames = &rawReader{m: m, left: size}
+	}
+
+	switch compression {
+	case Compression_NONE:
+		d.r = d.frames
+	case Compression_GZIP:
+		d.r = &gzipReader{frames: d.frames}
+	case Compression_ZSTD:
+		dec, err := zstd.NewReader(d.frames, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		d.r, d.free = dec, dec.Close
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", compression)
+	}
+	return d, nil
+}
+
+func (d *DataReader) Read(p []byte) (int, error) {
+	return d.r.Read(p)
+}
+
+// Close reads past whatever is left of the data, such as the trailer of
+// compressed data, or the rest of it if the receiver gave up early. It
+// doesn't close the connection, and if it returns nil, the next message can
+// be received: corrupted chunks are skipped over, and data the peer aborted
+// is over already.
+func (d *DataReader) Close() error {
+	d.free()
+	for {
+		_, err := io.Copy(io.Discard, d.frames)
+		switch err.(type) {
+		case *ChunkError:
+			continue
+		case *AbortedError:
+			return nil
+		}
+		return err
+	}
+}
+
+// WireBytes returns the number of bytes received over the connection so far.
+func (d *DataReader) WireBytes() int64 {
+	return d.frames.wireBytes()
+}
+
+// gzipReader decompresses gzip data. Unlike gzip.NewReader, it reads the
+// header on the first Read, so that an abort or a corrupted chunk in place of
+// it comes out of Read like anywhere else in the data.
+type gzipReader struct {
+	frames io.Reader
+	dec    *gzip.Reader
+	err    error
+}
+
+func (r *gzipReader) Read(p []byte) (int, error) {
+	if r.dec == nil && r.err == nil {
+		r.dec, r.err = gzip.NewReader(r.frames)
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.dec.Read(p)
+}
+
+// rawWriter sends the data as is.
+type rawWriter struct {
+	m *MessageHandler
+	n int64
+}
+
+func (w *rawWriter) Write(p []byte) (int, error) {
+	if err := w.m.WriteN(p); err != nil {
+		return 0, err
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func (w *rawWriter) end() error {
+	return nil
+}
+
+func (w *rawWriter) wireBytes() int64 {
+	return w.n
+}
+
+// rawReader reads data of a known size.
+type rawReader struct {
+	m    *MessageHandler
+	left uint64
+	n    int64
+}
+
+func (r *rawReader) Read(p []byte) (int, error) {
+	if r.left == 0 {
+		return 0, io.EOF
+	}
+	if uint64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+	n, err := r.m.Read(p)
+	r.left -= uint64(n)
+	r.n += int64(n)
+	return n, unexpectedEOF(err)
+}
+
+func (r *rawReader) wireBytes() int64 {
+	return r.n
+}
+
+// blockWriter sends each Write as a block.
+type blockWriter struct {
+	m *MessageHandler
+	n int64
+}
+
+func (w *blockWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		// An empty block would end the stream
+		return 0, nil
+	}
+	if err := w.block(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// end sends the empty block that ends the stream.
+func (w *blockWriter) end() error {
+	return w.block(nil)
+}
+
+func (w *blockWriter) block(p []byte) error {
+	prefix := make([]byte, 8)
+	binary.LittleEndian.PutUint64(prefix, uint64(len(p)))
+	if err := w.m.WriteN(prefix); err != nil {
+		return err
+	}
+	if err := w.m.WriteN(p); err != nil {
+		return err
+	}
+	w.n += int64(len(prefix) + len(p))
+	return nil
+}
+
+func (w *blockWriter) wireBytes() int64 {
+	return w.n
+}
+
+// blockReader reads the contents of blocks up to the empty one.
+type blockReader struct {
+	m    *MessageHandler
+	left uint64 // Bytes left in the current block
+	done bool   // Reached the empty block
+	n    int64
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	for r.left == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		prefix := make([]byte, 8)
+		if err := r.m.ReadN(prefix); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		r.n += int64(len(prefix))
+		r.left = binary.LittleEndian.Uint64(prefix)
+		r.done = r.left == 0
+	}
+
+	if uint64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+	n, err := r.m.Read(p)
+	r.left -= uint64(n)
+	r.n += int64(n)
+	return n, unexpectedEOF(err)
+}
+
+func (r *blockReader) wireBytes() int64 {
+	return r.n
+}
+
+// unexpectedEOF reports the connection closing in the middle of a stream as
+// the error it is.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
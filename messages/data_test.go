@@ -0,0 +1,45 @@
+package messages
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestAbortBeforeCompressedData(t *testing.T) {
+	for _, compression := range []Compression{Compression_NONE, Compression_GZIP, Compression_ZSTD} {
+		t.Run(compression.String(), func(t *testing.T) {
+			sender, receiver := pipe(t)
+
+			go func() {
+				w, err := sender.NewDataWriter(compression, true)
+				if err != nil {
+					return
+				}
+				w.Abort("disk on fire")
+				sender.SendResponse(OK("still here"))
+			}()
+
+			// The abort takes the place of the compressed stream's header
+			r, err := receiver.NewDataReader(compression, true, 100)
+			if err != nil {
+				t.Fatalf("NewDataReader = %v", err)
+			}
+			var aborted *AbortedError
+			if _, err := io.ReadAll(r); !errors.As(err, &aborted) {
+				t.Errorf("Read = %v, want an *AbortedError", err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close = %v, want nil", err)
+			}
+
+			wrapper, err := receiver.Receive()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := wrapper.GetResponse().GetMessage(); got != "still here" {
+				t.Errorf("next message is %q, want \"still here\"", got)
+			}
+		})
+	}
+}
@@ -2,123 +2,403 @@ package messages
 
 import (
 	"encoding/binary"
-	"log"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"sync"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 )
 
+// ProtocolVersion is the newest version of the protocol this package speaks,
+// and MinProtocolVersion the oldest. Version 1 is the protocol from before
+// Hello; version 2 adds it.
+const (
+	ProtocolVersion    = 2
+	MinProtocolVersion = 1
+)
+
+// DefaultMaxFrameSize is the largest message a MessageHandler sends, and the
+// largest it receives unless told otherwise. It leaves plenty of room for a
+// full page of LIST entries or a DataChunk.
+const DefaultMaxFrameSize = 16 << 20
+
+// MinMaxFrameSize is the smallest maximum frame size that still lets a full
+// DataChunk through.
+const MinMaxFrameSize = MaxChunkSize + 1024
+
+var (
+	// ErrFrameTooLarge is returned for a message over the maximum frame size.
+	// The rest of a message that is received isn't read, so the connection
+	// can't be used any more.
+	ErrFrameTooLarge = errors.New("message too large")
+
+	// ErrShortRead is returned when the connection closes partway through a
+	// message.
+	ErrShortRead = errors.New("connection closed in the middle of a message")
+
+	// ErrMalformed is returned for a message that isn't a valid Wrapper. It
+	// has been read in full, so the next message can still be received.
+	ErrMalformed = errors.New("malformed message")
+
+	// ErrTimeout is returned when the peer took longer than the Timeouts
+	// allow to send a message or take one in, and ErrStalled when file data
+	// stopped getting through. Either way the connection can't be used any
+	// more.
+	ErrTimeout = errors.New("timed out")
+	ErrStalled = errors.New("transfer stalled")
+)
+
+// Timeouts bound how long a MessageHandler waits on its peer. Zero values
+// mean no limit.
+type Timeouts struct {
+	// How long Receive waits for a message to start: the next request on a
+	// server, the reply to one on a client
+	Idle time.Duration
+
+	// How long the rest of a message may take to arrive once it has started,
+	// and how long sending one may take
+	Message time.Duration
+
+	// How long file data may go without any of it getting through, in either
+	// direction
+	Stall time.Duration
+}
+
 type MessageHandler struct {
-	conn net.Conn
+	conn         net.Conn
+	maxFrameSize uint64
+	timeouts     Timeouts
+
+	mu          sync.Mutex // Orders setting deadlines with Interrupt
+	interrupted bool
 }
 
 func NewMessageHandler(conn net.Conn) *MessageHandler {
 	m := &MessageHandler{
-		conn: conn,
+		conn:         conn,
+		maxFrameSize: DefaultMaxFrameSize,
 	}
 
 	return m
 }
 
-func (m *MessageHandler) ReadN(buf []byte) error {
-	bytesRead := uint64(0)
-	for bytesRead < uint64(len(buf)) {
-		n, err := m.conn.Read(buf[bytesRead:])
-		if err != nil {
-			return err
-		}
-		bytesRead += uint64(n)
+// SetMaxFrameSize changes the largest message Receive accepts. Messages sent
+// are held to DefaultMaxFrameSize, what the peer accepts by default, whatever
+// the size.
+func (m *MessageHandler) SetMaxFrameSize(size uint64) {
+	m.maxFrameSize = size
+}
+
+// SetTimeouts changes how long the MessageHandler waits on its peer from then
+// on.
+func (m *MessageHandler) SetTimeouts(timeouts Timeouts) {
+	m.timeouts = timeouts
+}
+
+// Interrupt makes whatever the MessageHandler is blocked on fail, along with
+// everything after it, until the connection's deadlines are set directly.
+// It may be called from any goroutine.
+func (m *MessageHandler) Interrupt() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interrupted = true
+	m.conn.SetDeadline(aLongTimeAgo)
+}
+
+// aLongTimeAgo is a deadline that has already passed, for interrupting I/O
+var aLongTimeAgo = time.Unix(1, 0)
+
+// setReadDeadline gives the next read timeout to finish, unless the
+// MessageHandler has been interrupted.
+func (m *MessageHandler) setReadDeadline(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.interrupted {
+		m.conn.SetReadDeadline(deadline(timeout))
 	}
-	return nil
 }
 
+// setWriteDeadline is setReadDeadline for writes.
+func (m *MessageHandler) setWriteDeadline(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.interrupted {
+		m.conn.SetWriteDeadline(deadline(timeout))
+	}
+}
+
+// deadline is when something that may take timeout must be done by.
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// timedOut reports a deadline set from the Timeouts passing as sentinel, with
+// what was being waited for. Interrupts and other errors are returned as they
+// are. Since the peer is out of step after a timeout, everything after it
+// fails right away, as after Interrupt.
+func (m *MessageHandler) timedOut(err error, sentinel error, waiting string, timeout time.Duration) error {
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.interrupted {
+		return err
+	}
+	m.interrupted = true
+	m.conn.SetDeadline(aLongTimeAgo)
+	return fmt.Errorf("%w: %s %v", sentinel, waiting, timeout)
+}
+
+// ReadN fills buf with file data. Like io.ReadFull, it returns io.EOF if the
+// connection was closed before anything was read, and io.ErrUnexpectedEOF if
+// it was closed partway through.
+func (m *MessageHandler) ReadN(buf []byte) error {
+	return m.readFull(buf, m.timeouts.Stall, ErrStalled, "no data for")
+}
+
+// readFull fills buf, failing with sentinel if it takes longer than timeout.
+func (m *MessageHandler) readFull(buf []byte, timeout time.Duration, sentinel error, waiting string) error {
+	m.setReadDeadline(timeout)
+	_, err := io.ReadFull(m.conn, buf)
+	return m.timedOut(err, sentinel, waiting, timeout)
+}
+
+// Read reads file data, failing with ErrStalled if none arrives for the
+// Stall timeout.
 func (m *MessageHandler) Read(p []byte) (n int, err error) {
-	return m.conn.Read(p)
+	m.setReadDeadline(m.timeouts.Stall)
+	n, err = m.conn.Read(p)
+	return n, m.timedOut(err, ErrStalled, "no data for", m.timeouts.Stall)
 }
 
 func (m *MessageHandler) Write(p []byte) (n int, err error) {
-	return m.conn.Write(p)
+	if err := m.WriteN(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
 
+// WriteN writes all of buf as file data, failing with ErrStalled if the peer
+// takes none of it in for the Stall timeout.
 func (m *MessageHandler) WriteN(buf []byte) error {
+	return m.writeFull(buf, m.timeouts.Stall, ErrStalled, "peer took no data for")
+}
+
+// writeFull writes all of buf, failing with sentinel if it takes longer than
+// timeout.
+func (m *MessageHandler) writeFull(buf []byte, timeout time.Duration, sentinel error, waiting string) error {
+	m.setWriteDeadline(timeout)
 	bytesWritten := uint64(0)
 	for bytesWritten < uint64(len(buf)) {
 		n, err := m.conn.Write(buf[bytesWritten:])
 		if err != nil {
-			return err
+			return m.timedOut(err, sentinel, waiting, timeout)
 		}
 		bytesWritten += uint64(n)
 	}
 	return nil
 }
 
+// Send sends a message, taking the Message timeout at most.
 func (m *MessageHandler) Send(wrapper *Wrapper) error {
+	return m.send(wrapper, m.timeouts.Message, ErrTimeout, "sending a message took over")
+}
+
+// send sends a message, failing with sentinel if it takes longer than
+// timeout.
+func (m *MessageHandler) send(wrapper *Wrapper, timeout time.Duration, sentinel error, waiting string) error {
 	serialized, err := proto.Marshal(wrapper)
 	if err != nil {
 		return err
 	}
+	if len(serialized) > DefaultMaxFrameSize {
+		return fmt.Errorf("%w: %d bytes, at most %d", ErrFrameTooLarge, len(serialized), DefaultMaxFrameSize)
+	}
 
-	prefix := make([]byte, 8)
-	binary.LittleEndian.PutUint64(prefix, uint64(len(serialized)))
-	m.WriteN(prefix)
-	m.WriteN(serialized)
-
-	return nil
+	// One write, so the prefix doesn't go out in a packet of its own
+	frame := make([]byte, 8, 8+len(serialized))
+	binary.LittleEndian.PutUint64(frame, uint64(len(serialized)))
+	return m.writeFull(append(frame, serialized...), timeout, sentinel, waiting)
 }
 
+// Receive reads the next message, waiting for the Idle timeout at most for it
+// to start. It returns io.EOF if the peer closed the connection in between
+// messages.
 func (m *MessageHandler) Receive() (*Wrapper, error) {
+	return m.receive(m.timeouts.Idle, ErrTimeout, "idle for")
+}
+
+// receive reads the next message, failing with sentinel if it doesn't start
+// within wait.
+func (m *MessageHandler) receive(wait time.Duration, sentinel error, waiting string) (*Wrapper, error) {
 	prefix := make([]byte, 8)
-	m.ReadN(prefix)
+	if err := m.readFull(prefix, wait, sentinel, waiting); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, shortRead(err)
+	}
 
+	// Don't trust the peer with how much memory to allocate
 	payloadSize := binary.LittleEndian.Uint64(prefix)
+	if payloadSize > m.maxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d", ErrFrameTooLarge, payloadSize, m.maxFrameSize)
+	}
 	payload := make([]byte, payloadSize)
-	m.ReadN(payload)
+	if err := m.readFull(payload, m.timeouts.Message, ErrTimeout, "receiving a message took over"); err != nil {
+		return nil, shortRead(err)
+	}
 
 	wrapper := &Wrapper{}
-	err := proto.Unmarshal(payload, wrapper)
-	return wrapper, err
+	if err := proto.Unmarshal(payload, wrapper); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return wrapper, nil
+}
+
+// shortRead reports the connection closing partway through a message as
+// ErrShortRead. Other errors are returned as they are.
+func shortRead(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %w", ErrShortRead, io.ErrUnexpectedEOF)
+	}
+	return err
 }
 
 func (m *MessageHandler) Close() {
 	m.conn.Close()
 }
 
-func (m *MessageHandler) SendStorageRequest(fileName string, size uint64) error {
-	msg := StorageRequest{FileName: fileName, Size: size}
+// SendHello sends a Hello, the client's first message or the server's reply
+// to it.
+func (m *MessageHandler) SendHello(hello *Hello) error {
+	wrapper := &Wrapper{
+		Msg: &Wrapper_Hello{Hello: hello},
+	}
+	return m.Send(wrapper)
+}
+
+// SendStorageRequest starts an upload, or one range of it.
+func (m *MessageHandler) SendStorageRequest(request *StorageRequest) error {
+	wrapper := &Wrapper{
+		Msg: &Wrapper_StorageReq{StorageReq: request},
+	}
+	return m.Send(wrapper)
+}
+
+// SendRetrievalRequest starts a download, or one range of it.
+func (m *MessageHandler) SendRetrievalRequest(request *RetrievalRequest) error {
+	wrapper := &Wrapper{
+		Msg: &Wrapper_RetrievalReq{RetrievalReq: request},
+	}
+	return m.Send(wrapper)
+}
+
+func (m *MessageHandler) SendListRequest(prefix string, pageSize uint32, pageToken string) error {
+	msg := ListRequest{Prefix: prefix, PageSize: pageSize, PageToken: pageToken}
+	wrapper := &Wrapper{
+		Msg: &Wrapper_ListReq{ListReq: &msg},
+	}
+	return m.Send(wrapper)
+}
+
+func (m *MessageHandler) SendDeleteRequest(fileName string) error {
+	msg := DeleteRequest{FileName: fileName}
 	wrapper := &Wrapper{
-		Msg: &Wrapper_StorageReq{StorageReq: &msg},
+		Msg: &Wrapper_DeleteReq{DeleteReq: &msg},
 	}
 	return m.Send(wrapper)
 }
 
-func (m *MessageHandler) SendRetrievalRequest(fileName string) error {
-	msg := RetrievalRequest{FileName: fileName}
+func (m *MessageHandler) SendAuthRequest(token string) error {
+	msg := AuthRequest{Token: token}
 	wrapper := &Wrapper{
-		Msg: &Wrapper_RetrievalReq{RetrievalReq: &msg},
+		Msg: &Wrapper_AuthReq{AuthReq: &msg},
 	}
 	return m.Send(wrapper)
 }
 
-func (m *MessageHandler) SendChecksumVerification(checksum []byte) error {
-	checkMsg := ChecksumVerification{Checksum: checksum}
+func (m *MessageHandler) SendChecksumVerification(algorithm HashAlgorithm, checksum []byte) error {
+	checkMsg := ChecksumVerification{Checksum: checksum, Algorithm: algorithm}
 	checkWrapper := &Wrapper{
 		Msg: &Wrapper_Checksum{Checksum: &checkMsg},
 	}
 	return m.Send(checkWrapper)
 }
 
-func (m *MessageHandler) SendResponse(ok bool, str string) error {
-	msg := Response{Ok: ok, Message: str}
+// OK is the Response of a request that succeeded.
+func OK(message string) *Response {
+	return &Response{Ok: true, Message: message}
+}
+
+// Refusal is the Response of a request that was refused for the reason code
+// stands for.
+func Refusal(code ErrorCode, message string) *Response {
+	return &Response{Message: message, Code: code}
+}
+
+func (m *MessageHandler) SendResponse(resp *Response) error {
 	wrapper := &Wrapper{
-		Msg: &Wrapper_Response{Response: &msg},
+		Msg: &Wrapper_Response{Response: resp},
 	}
 
 	return m.Send(wrapper)
 }
 
-func (m *MessageHandler) SendRetrievalResponse(ok bool, str string, size uint64) error {
-	resp := Response{Ok: ok, Message: str}
-	msg := RetrievalResponse{Resp: &resp, Size: size}
+func (m *MessageHandler) SendStorageResponse(resp *Response, offset uint64, compression Compression, chunked bool) error {
+	msg := StorageResponse{Resp: resp, Offset: offset, Compression: compression, Chunked: chunked}
+	wrapper := &Wrapper{
+		Msg: &Wrapper_StorageResp{StorageResp: &msg},
+	}
+
+	return m.Send(wrapper)
+}
+
+// SendResumeResponse accepts a resumable upload that continues at offset,
+// with the checksum of the bytes the server already holds.
+func (m *MessageHandler) SendResumeResponse(str string, offset uint64, prefixChecksum []byte, compression Compression, chunked bool) error {
+	resp := Response{Ok: true, Message: str}
+	msg := StorageResponse{Resp: &resp, Offset: offset, PrefixChecksum: prefixChecksum, Compression: compression, Chunked: chunked}
+	wrapper := &Wrapper{
+		Msg: &Wrapper_StorageResp{StorageResp: &msg},
+	}
+
+	return m.Send(wrapper)
+}
+
+// SendRangeResponse accepts the upload of a range of a file.
+func (m *MessageHandler) SendRangeResponse(str string, length uint64, compression Compression, chunked bool) error {
+	resp := Response{Ok: true, Message: str}
+	msg := StorageResponse{Resp: &resp, Compression: compression, Chunked: chunked, RangeLength: length}
+	wrapper := &Wrapper{
+		Msg: &Wrapper_StorageResp{StorageResp: &msg},
+	}
+
+	return m.Send(wrapper)
+}
+
+// SendStoredResponse tells the client of a resumable upload where its file
+// ended up.
+func (m *MessageHandler) SendStoredResponse(str string, fileName string, version uint32) error {
+	resp := Response{Ok: true, Message: str}
+	msg := StorageResponse{Resp: &resp, FileName: fileName, Version: version}
+	wrapper := &Wrapper{
+		Msg: &Wrapper_StorageResp{StorageResp: &msg},
+	}
+
+	return m.Send(wrapper)
+}
+
+func (m *MessageHandler) SendRetrievalResponse(resp *Response, size uint64, compression Compression, chunked bool, rangeChecksum bool) error {
+	msg := RetrievalResponse{Resp: resp, Size: size, Compression: compression, Chunked: chunked, RangeChecksum: rangeChecksum}
 	wrapper := &Wrapper{
 		Msg: &Wrapper_RetrievalResp{RetrievalResp: &msg},
 	}
@@ -126,23 +406,40 @@ func (m *MessageHandler) SendRetrievalResponse(ok bool, str string, size uint64)
 	return m.Send(wrapper)
 }
 
-func (m *MessageHandler) ReceiveResponse() (bool, string) {
-	resp, err := m.Receive()
-	if err != nil {
-		return false, ""
+func (m *MessageHandler) SendListResponse(resp *Response, entries []*FileEntry, nextPageToken string) error {
+	msg := ListResponse{Resp: resp, Entries: entries, NextPageToken: nextPageToken}
+	wrapper := &Wrapper{
+		Msg: &Wrapper_ListResp{ListResp: &msg},
 	}
 
-	log.Println(resp.GetResponse().Message)
-	return resp.GetResponse().Ok, resp.GetResponse().Message
+	return m.Send(wrapper)
 }
 
-func (m *MessageHandler) ReceiveRetrievalResponse() (bool, string, uint64) {
-	resp, err := m.Receive()
-	if err != nil {
-		return false, "", 0
+func (m *MessageHandler) SendAuthResponse(resp *Response, user string) error {
+	msg := AuthResponse{Resp: resp, User: user}
+	wrapper := &Wrapper{
+		Msg: &Wrapper_AuthResp{AuthResp: &msg},
 	}
 
-	rr := resp.GetRetrievalResp().GetResp()
-	log.Println(rr.Message)
-	return rr.Ok, rr.Message, resp.GetRetrievalResp().Size
+	return m.Send(wrapper)
+}
+
+// StatusOf returns the Response carried by any reply from the server, or nil
+// if the message isn't a reply.
+func StatusOf(wrapper *Wrapper) *Response {
+	switch msg := wrapper.GetMsg().(type) {
+	case *Wrapper_Response:
+		return msg.Response
+	case *Wrapper_StorageResp:
+		return msg.StorageResp.GetResp()
+	case *Wrapper_RetrievalResp:
+		return msg.RetrievalResp.GetResp()
+	case *Wrapper_ListResp:
+		return msg.ListResp.GetResp()
+	case *Wrapper_AuthResp:
+		return msg.AuthResp.GetResp()
+	case *Wrapper_Hello:
+		return msg.Hello.GetResp()
+	}
+	return nil
 }
@@ -0,0 +1,90 @@
+package messages
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// pipe returns the two ends of an in-memory connection, each with a
+// MessageHandler.
+func pipe(t *testing.T) (*MessageHandler, *MessageHandler) {
+	t.Helper()
+	a, b := net.Pipe()
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return NewMessageHandler(a), NewMessageHandler(b)
+}
+
+// writeFrame writes a length prefix claiming size bytes followed by payload,
+// then closes the connection if close is set, without waiting for the other
+// end to read it.
+func writeFrame(m *MessageHandler, size uint64, payload []byte, close bool) {
+	go func() {
+		prefix := make([]byte, 8)
+		binary.LittleEndian.PutUint64(prefix, size)
+		m.conn.Write(append(prefix, payload...))
+		if close {
+			m.conn.Close()
+		}
+	}()
+}
+
+func TestReceiveFrameTooLarge(t *testing.T) {
+	client, server := pipe(t)
+	server.SetMaxFrameSize(MinMaxFrameSize)
+
+	writeFrame(client, MinMaxFrameSize+1, nil, false)
+	if _, err := server.Receive(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Receive = %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestMaxFrameSizeOnlyLimitsReceiving(t *testing.T) {
+	client, server := pipe(t)
+	server.SetMaxFrameSize(MinMaxFrameSize)
+
+	// A listing larger than what the server takes in can still go out
+	message := strings.Repeat("x", 2*MinMaxFrameSize)
+	go server.SendResponse(OK(message))
+	reply, err := client.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.GetResponse().GetMessage() != message {
+		t.Errorf("received a message of %d bytes, want %d", len(reply.GetResponse().GetMessage()), len(message))
+	}
+}
+
+func TestReceiveShortRead(t *testing.T) {
+	client, server := pipe(t)
+
+	writeFrame(client, 100, make([]byte, 10), true)
+	if _, err := server.Receive(); !errors.Is(err, ErrShortRead) {
+		t.Errorf("Receive = %v, want ErrShortRead", err)
+	}
+}
+
+func TestReceiveMalformed(t *testing.T) {
+	client, server := pipe(t)
+
+	garbage := []byte{0xff, 0xff, 0xff}
+	writeFrame(client, uint64(len(garbage)), garbage, false)
+	if _, err := server.Receive(); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("Receive = %v, want ErrMalformed", err)
+	}
+
+	// The malformed message was read in full, so the next one gets through
+	go client.SendResponse(OK("still here"))
+	wrapper, err := server.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wrapper.GetResponse().GetMessage(); got != "still here" {
+		t.Errorf("next message is %q, want \"still here\"", got)
+	}
+}
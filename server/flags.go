@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"file-transfer/auth"
+	"file-transfer/messages"
+	"file-transfer/storage"
+	"file-transfer/util"
+)
+
+// Flags are the command line settings the server binaries share. Each binary
+// registers them, builds its Server with NewServer and only prints its own
+// banner.
+type Flags struct {
+	CertFile        string
+	KeyFile         string
+	ClientCAFile    string // Client certificates are required if set
+	CredentialsFile string
+	ACLFile         string
+	Dedup           bool
+	S3              storage.S3Config // The keys come from the environment
+	Limits          Limits
+	Timeouts        messages.Timeouts
+	ShutdownTimeout time.Duration
+	PartialExpiry   time.Duration
+}
+
+// FlagsUsage lists the flags RegisterFlags defines, for usage messages.
+const FlagsUsage = "[-cert file -key file [-client-ca file]] [-credentials file] [-acl file] [-dedup] [-s3-endpoint host:port [-s3-insecure]] [-max-file-size bytes] [-max-message-size bytes] [-max-conns n] [-max-conns-per-ip n] [-retry-after duration] [-idle-timeout duration] [-message-timeout duration] [-stall-timeout duration] [-shutdown-timeout duration] [-partial-expiry duration]"
+
+// RegisterFlags defines the shared flags on flags. The returned Flags are
+// filled in as it is parsed.
+func RegisterFlags(flags *flag.FlagSet) *Flags {
+	f := &Flags{}
+	flags.StringVar(&f.CertFile, "cert", "", "TLS certificate file (enables TLS together with -key)")
+	flags.StringVar(&f.KeyFile, "key", "", "TLS private key file")
+	flags.StringVar(&f.ClientCAFile, "client-ca", "", "only accept clients with a certificate signed by this CA (mutual TLS)")
+	flags.StringVar(&f.CredentialsFile, "credentials", "", "file of \"token user [groups]\" lines; clients must authenticate with one of the tokens")
+	flags.StringVar(&f.ACLFile, "acl", "", "file of \"user|@group|* rwd\" lines granting access; without it everyone may do everything")
+	flags.DurationVar(&f.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "how long to let transfers in progress finish after SIGINT or SIGTERM")
+	flags.DurationVar(&f.PartialExpiry, "partial-expiry", 24*time.Hour, "how long to keep the data of an interrupted upload for the client to resume from (0 for no limit)")
+	flags.BoolVar(&f.Dedup, "dedup", false, "store identical files once, as hard links to a single copy")
+	flags.StringVar(&f.S3.Endpoint, "s3-endpoint", "s3.amazonaws.com", "S3-compatible service for an s3://bucket[/prefix] download dir; keys are read from $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY")
+	flags.BoolVar(&f.S3.Insecure, "s3-insecure", false, "talk to the S3 endpoint over plain HTTP")
+	flags.Uint64Var(&f.Limits.MaxFileSize, "max-file-size", 0, "largest file clients may store, in bytes (0 for no limit)")
+	flags.Uint64Var(&f.Limits.MaxMessageSize, "max-message-size", 0, fmt.Sprintf("largest message clients may send, in bytes, at least %d (0 for the default of 16 MiB)", messages.MinMaxFrameSize))
+	flags.IntVar(&f.Limits.MaxConns, "max-conns", 0, "most clients served at once; the rest are told to retry later (0 for no limit)")
+	flags.IntVar(&f.Limits.MaxConnsPerIP, "max-conns-per-ip", 0, "most clients served at once from a single IP address (0 for no limit)")
+	flags.DurationVar(&f.Limits.RetryAfter, "retry-after", defaultRetryAfter, "how long clients refused for -max-conns or -max-conns-per-ip are told to wait")
+	flags.DurationVar(&f.Timeouts.Idle, "idle-timeout", 5*time.Minute, "how long to wait for a client's next request before closing the connection (0 for no limit)")
+	flags.DurationVar(&f.Timeouts.Message, "message-timeout", time.Minute, "how long sending or receiving a single message may take (0 for no limit)")
+	flags.DurationVar(&f.Timeouts.Stall, "stall-timeout", time.Minute, "how long a transfer may go without any data getting through before it is given up on (0 for no limit)")
+	return f
+}
+
+// NewServer builds the server the flags describe, keeping its files in dir, a
+// local directory or s3://bucket[/prefix].
+func (f *Flags) NewServer(dir string) (*Server, error) {
+	srv := &Server{Limits: f.Limits, Timeouts: f.Timeouts, PartialExpiry: f.PartialExpiry}
+
+	var err error
+	if f.CredentialsFile != "" {
+		srv.Credentials, err = auth.LoadCredentials(f.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.ACLFile != "" {
+		srv.ACL, err = auth.LoadACL(f.ACLFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s3 := f.S3
+	s3.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	s3.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	srv.Storage, err = storage.OpenLocation(dir, f.Dedup, s3)
+	if err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+// TLS reports whether the server is reached over TLS, and whether clients
+// must then present a certificate.
+func (f *Flags) TLS() (enabled bool, clientCerts bool) {
+	return f.CertFile != "", f.ClientCAFile != ""
+}
+
+// Listen listens on port, over TLS if the flags ask for it.
+func (f *Flags) Listen(port string) (net.Listener, error) {
+	tlsConfig, err := util.ServerTLSConfig(f.CertFile, f.KeyFile, f.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return util.Listen(port, tlsConfig)
+}
+
+// ServeUntilSignal serves srv on l until SIGINT or SIGTERM, then lets the
+// transfers in progress finish for up to ShutdownTimeout before returning. A
+// second signal exits right away. notify is told about the signal first,
+// e.g. to say the server is shutting down.
+func (f *Flags) ServeUntilSignal(srv *Server, l net.Listener, notify func(os.Signal)) error {
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		sig := <-signals
+		signal.Stop(signals)
+
+		notify(sig)
+		ctx, cancel := context.WithTimeout(context.Background(), f.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			srv.logger().Println("Interrupted the transfers still in progress:", err)
+		}
+		close(stopped)
+	}()
+
+	if err := srv.Serve(l); err != ErrServerClosed {
+		return err
+	}
+	<-stopped
+	return nil
+}
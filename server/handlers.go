@@ -0,0 +1,659 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"math"
+	"strconv"
+	"syscall"
+	"time"
+
+	"file-transfer/auth"
+	"file-transfer/messages"
+	"file-transfer/storage"
+	"file-transfer/util"
+)
+
+// userName returns the name hooks are given for the connection's user.
+func (c *conn) userName() string {
+	if c.user == nil {
+		return ""
+	}
+	return c.user.Name
+}
+
+// allowed checks the ACL, telling the client when it was denied.
+func (c *conn) allowed(perm auth.Permission) bool {
+	if c.server.ACL.Allows(c.user, perm) {
+		return true
+	}
+
+	c.server.logger().Println("Denied", perm, "access")
+	c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_PERMISSION_DENIED, "Permission denied: "+perm.String()+" access required."))
+	return false
+}
+
+// clientError describes err to the client in terms of the name it asked for,
+// rather than the path on the server.
+func clientError(name string, err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return name + ": " + pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// refusal is clientError as a Response, with the code that fits err.
+func refusal(name string, err error) *messages.Response {
+	resp := messages.Refusal(errorCode(err), clientError(name, err))
+	resp.Details = map[string]string{"name": name}
+	return resp
+}
+
+// errorCode tells the client what kind of error err is.
+func errorCode(err error) messages.ErrorCode {
+	var invalid invalidRequest
+	var chunkErr *messages.ChunkError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return messages.ErrorCode_NOT_FOUND
+	case errors.Is(err, fs.ErrExist):
+		return messages.ErrorCode_ALREADY_EXISTS
+	case errors.Is(err, fs.ErrPermission), errors.Is(err, storage.ErrOutsideRoot), errors.Is(err, storage.ErrHidden):
+		return messages.ErrorCode_PERMISSION_DENIED
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return messages.ErrorCode_QUOTA_EXCEEDED
+	case errors.Is(err, errNoRanges):
+		return messages.ErrorCode_UNSUPPORTED
+	case errors.As(err, &invalid), errors.Is(err, storage.ErrNotRegular):
+		return messages.ErrorCode_INVALID_REQUEST
+	case errors.As(err, &chunkErr), errors.Is(err, storage.ErrCorrupt):
+		return messages.ErrorCode_CHECKSUM_MISMATCH
+	case errors.Is(err, storage.ErrBusy):
+		return messages.ErrorCode_UNAVAILABLE
+	}
+	return messages.ErrorCode_INTERNAL
+}
+
+// invalidRequest is what is wrong with a request itself, as opposed to an
+// error carrying it out.
+type invalidRequest string
+
+func (e invalidRequest) Error() string {
+	return string(e)
+}
+
+func (c *conn) handleAuth(request *messages.AuthRequest) error {
+	s := c.server
+	if s.Credentials == nil {
+		return c.msgHandler.SendAuthResponse(messages.OK("Authentication not required."), "")
+	}
+
+	user, ok := s.Credentials.Authenticate(request.GetToken())
+	if !ok {
+		s.logger().Println("Rejected invalid token")
+		c.msgHandler.SendAuthResponse(messages.Refusal(messages.ErrorCode_UNAUTHENTICATED, "Invalid token."), "")
+		return errDisconnect
+	}
+
+	// Each user only sees their own directory
+	userStore, err := storage.Sub(s.Storage, user.Name)
+	if err != nil {
+		c.msgHandler.SendAuthResponse(messages.Refusal(messages.ErrorCode_INTERNAL, "Unable to open user directory."), "")
+		return err
+	}
+	c.user = user
+	c.store = userStore
+
+	s.logger().Println("Authenticated", user.Name)
+	if s.Hooks.OnAuthenticated != nil {
+		s.Hooks.OnAuthenticated(c.netConn.RemoteAddr(), user.Name)
+	}
+	return c.msgHandler.SendAuthResponse(messages.OK("Authenticated as "+user.Name+"."), user.Name)
+}
+
+// handleHello tells the client what the server supports, refusing clients
+// whose protocol versions don't overlap with the server's.
+func (c *conn) handleHello(hello *messages.Hello) error {
+	s := c.server
+	reply := s.hello()
+	switch {
+	case hello.GetVersion() < messages.MinProtocolVersion:
+		reply.Resp = messages.Refusal(messages.ErrorCode_UNSUPPORTED, fmt.Sprintf("Protocol version %d is no longer supported; this server speaks versions %d to %d.",
+			hello.GetVersion(), messages.MinProtocolVersion, messages.ProtocolVersion))
+	case hello.GetMinVersion() > messages.ProtocolVersion:
+		reply.Resp = messages.Refusal(messages.ErrorCode_UNSUPPORTED, fmt.Sprintf("This server speaks protocol versions up to %d, but the client needs %d or later.",
+			messages.ProtocolVersion, hello.GetMinVersion()))
+	}
+	if reply.Resp != nil {
+		s.logger().Println("Refusing client:", reply.Resp.Message)
+		c.msgHandler.SendHello(reply)
+		return errDisconnect
+	}
+
+	version := min(hello.GetVersion(), messages.ProtocolVersion)
+	s.logger().Println("Speaking protocol version", version)
+	reply.Resp = messages.OK(fmt.Sprintf("Speaking protocol version %d.", version))
+	return c.msgHandler.SendHello(reply)
+}
+
+// hello describes what the server supports, which depends on its storage.
+func (s *Server) hello() *messages.Hello {
+	_, resume := s.Storage.(storage.Resumer)
+	_, ranges := s.Storage.(storage.Assembler)
+	return &messages.Hello{
+		Version:      messages.ProtocolVersion,
+		MinVersion:   messages.MinProtocolVersion,
+		Hashes:       util.Hashes(),
+		Compressions: util.Compressions(),
+		Resume:       resume,
+		Chunked:      true,
+		Ranges:       ranges,
+	}
+}
+
+func (c *conn) handleStorage(request *messages.StorageRequest) error {
+	s := c.server
+	s.logger().Println("Attempting to store", request.GetFileName())
+
+	// Resumable uploads are answered with the offset to continue from
+	refuse := func(resp *messages.Response) error {
+		if request.GetResume() {
+			return c.msgHandler.SendStorageResponse(resp, 0, messages.Compression_NONE, false)
+		}
+		return c.msgHandler.SendResponse(resp)
+	}
+
+	if !c.allowed(auth.Write) {
+		return nil
+	}
+	if s.Limits.MaxFileSize > 0 && request.GetSize() > s.Limits.MaxFileSize {
+		resp := messages.Refusal(messages.ErrorCode_QUOTA_EXCEEDED, fmt.Sprintf("%s: larger than the %d byte limit", request.GetFileName(), s.Limits.MaxFileSize))
+		resp.Details = map[string]string{"name": request.GetFileName(), "limit": strconv.FormatUint(s.Limits.MaxFileSize, 10)}
+		return refuse(resp)
+	}
+	if request.GetResume() && request.GetRangeLength() > 0 {
+		return c.storeRange(request)
+	}
+
+	if err := c.checkConflict(request); err != nil {
+		return refuse(refusal(request.GetFileName(), err))
+	}
+
+	hash, err := util.NewHash(request.GetHash())
+	if err != nil {
+		return refuse(messages.Refusal(messages.ErrorCode_UNSUPPORTED, err.Error()))
+	}
+
+	// Skip the upload if we already have the content under another name
+	if w := c.existing(request, hash); w != nil {
+		s.logger().Println("Already have the content of", request.GetFileName())
+		c.msgHandler.SendStorageResponse(messages.OK("Already stored"), request.GetSize(), messages.Compression_NONE, false)
+		return c.finishStorage(request, w, hash.Sum(nil))
+	}
+
+	// Only resumable uploads get a reply that can say how the data is sent
+	compression := messages.Compression_NONE
+	chunked := false
+	if request.GetResume() {
+		compression = supported(request.GetCompression())
+		chunked = request.GetChunked()
+	}
+
+	// Nobody can get the file until it has been checked and committed
+	w, offset, err := c.create(request, hash)
+	if err != nil {
+		return refuse(refusal(request.GetFileName(), err))
+	}
+
+	if request.GetResume() {
+		var prefix []byte
+		if request.GetAssemble() {
+			s.logger().Println("Assembling", request.GetFileName(), "from ranges")
+		} else if offset > 0 {
+			s.logger().Printf("Resuming %s at offset %d\n", request.GetFileName(), offset)
+			// Lets the client check that what we hold is the start of its file
+			prefix = hash.Sum(nil)
+		}
+		err = c.msgHandler.SendResumeResponse("Ready for data", offset, prefix, compression, chunked)
+	} else {
+		err = c.msgHandler.SendResponse(messages.OK("Ready for data"))
+	}
+	if err != nil {
+		w.Close()
+		return c.interrupted(request, err)
+	}
+
+	/* Write and checksum as we go */
+	inSync, err := c.receiveData(io.MultiWriter(w, hash), request.GetSize()-offset, compression, chunked, request.GetFileName())
+	if err != nil {
+		// A resumable upload keeps what arrived so the client can continue later
+		w.Close()
+		if !inSync {
+			return c.interrupted(request, err)
+		}
+		return c.rejectData(request, err)
+	}
+
+	return c.finishStorage(request, w, hash.Sum(nil))
+}
+
+// existing returns a Writer already holding the content of a resumable
+// upload, if the storage deduplicates and has that content under another
+// name. The content is fed through hash.
+func (c *conn) existing(request *messages.StorageRequest, hash hash.Hash) storage.Writer {
+	d, ok := c.store.(storage.Deduplicator)
+	if !ok || !request.GetResume() {
+		return nil
+	}
+
+	attrs := storage.Attributes{Mode: request.GetMode(), ModTime: request.GetModTime()}
+	w, err := d.Existing(request.GetFileName(), request.GetSize(), request.GetSha256(), attrs, hash)
+	if err != nil {
+		// Upload it after all
+		c.server.logger().Println("error looking up existing content:", err)
+		hash.Reset()
+		return nil
+	}
+	return w
+}
+
+// create starts the upload for a StorageRequest. It returns the offset to
+// continue from, which is past the data kept from an earlier attempt if the
+// upload is resumable and the storage supports that, and at the end for one
+// assembled from ranges.
+func (c *conn) create(request *messages.StorageRequest, hash hash.Hash) (storage.Writer, uint64, error) {
+	if request.GetResume() && request.GetAssemble() {
+		a, ok := c.store.(storage.Assembler)
+		if !ok {
+			return nil, 0, errNoRanges
+		}
+		w, err := a.Assemble(request.GetFileName(), request.GetSize(), hash)
+		return w, request.GetSize(), err
+	}
+
+	if r, ok := c.store.(storage.Resumer); ok && request.GetResume() {
+		// Checksums what we already have
+		return r.Resume(request.GetFileName(), request.GetSize(), hash)
+	}
+
+	w, err := c.store.Create(request.GetFileName(), request.GetSize())
+	return w, 0, err
+}
+
+// errNoRanges refuses ranges sent to a storage that can't assemble them.
+var errNoRanges = errors.New("this server can't assemble uploads from ranges")
+
+// storeRange writes one range of a file uploaded over several connections in
+// place. The checksum the client sends after it covers just the range; the
+// whole file is checked once it is assembled.
+func (c *conn) storeRange(request *messages.StorageRequest) error {
+	s := c.server
+	name := request.GetFileName()
+	refuse := func(resp *messages.Response) error {
+		return c.msgHandler.SendStorageResponse(resp, 0, messages.Compression_NONE, false)
+	}
+
+	a, ok := c.store.(storage.Assembler)
+	if !ok {
+		return refuse(refusal(name, errNoRanges))
+	}
+	// Better now than once all the ranges have been sent
+	if err := c.checkConflict(request); err != nil {
+		return refuse(refusal(name, err))
+	}
+	offset, length := request.GetRangeOffset(), request.GetRangeLength()
+	if offset > request.GetSize() || length > request.GetSize()-offset {
+		return refuse(messages.Refusal(messages.ErrorCode_INVALID_REQUEST, fmt.Sprintf("range %d-%d is beyond the end of the file (%d bytes)", offset, offset+length, request.GetSize())))
+	}
+
+	hash, err := util.NewHash(request.GetHash())
+	if err != nil {
+		return refuse(messages.Refusal(messages.ErrorCode_UNSUPPORTED, err.Error()))
+	}
+
+	w, err := a.WriteRange(name, request.GetSize(), offset)
+	if err != nil {
+		return refuse(refusal(name, err))
+	}
+	defer w.Close()
+
+	s.logger().Printf("Receiving bytes %d-%d of %s\n", offset, offset+length, name)
+	compression := supported(request.GetCompression())
+	if err := c.msgHandler.SendRangeResponse("Ready for data", length, compression, request.GetChunked()); err != nil {
+		return c.interrupted(request, err)
+	}
+
+	inSync, err := c.receiveData(io.MultiWriter(w, hash), length, compression, request.GetChunked(), name)
+	if err != nil {
+		if !inSync {
+			return c.interrupted(request, err)
+		}
+		return c.rejectData(request, err)
+	}
+
+	serverCheck := &messages.ChecksumVerification{Algorithm: request.GetHash(), Checksum: hash.Sum(nil)}
+	clientCheckMsg, err := c.msgHandler.Receive()
+	if err != nil && !errors.Is(err, messages.ErrMalformed) {
+		return fmt.Errorf("error receiving checksum: %w", err)
+	}
+	if !util.VerifyChecksum(serverCheck, clientCheckMsg.GetChecksum()) {
+		s.logger().Printf("FAILED to store bytes %d-%d of %s. Invalid checksum: server %s, client %s\n", offset, offset+length, name, util.FormatChecksum(serverCheck), util.FormatChecksum(clientCheckMsg.GetChecksum()))
+		return c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_CHECKSUM_MISMATCH, "Checksum mismatch"))
+	}
+	if err := w.Close(); err != nil {
+		return c.msgHandler.SendResponse(refusal(name, err))
+	}
+	return c.msgHandler.SendResponse(messages.OK("Range stored"))
+}
+
+// supported returns the compression a client asked for if the server knows
+// it, and no compression otherwise.
+func supported(compression messages.Compression) messages.Compression {
+	if _, ok := messages.Compression_name[int32(compression)]; !ok {
+		return messages.Compression_NONE
+	}
+	return compression
+}
+
+// receiveData copies size bytes of file data from the client to w,
+// decompressing them on the way if they are sent compressed. When it fails,
+// inSync says whether the rest of the data could be skipped, leaving the
+// connection usable, which chunked data allows.
+func (c *conn) receiveData(w io.Writer, size uint64, compression messages.Compression, chunked bool, name string) (inSync bool, err error) {
+	r, err := c.msgHandler.NewDataReader(compression, chunked, size)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := io.CopyN(w, r, int64(size))
+	if err == io.EOF {
+		err = invalidRequest(fmt.Sprintf("data ended after %d of %d bytes", n, size))
+	}
+	if err == nil {
+		if extra, _ := io.CopyN(io.Discard, r, 1); extra > 0 {
+			err = invalidRequest(fmt.Sprintf("more data than the %d bytes announced", size))
+		}
+	}
+	closeErr := r.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return chunked && closeErr == nil, err
+	}
+
+	if compression != messages.Compression_NONE || chunked {
+		c.server.logger().Printf("Received %d bytes of %s as %d (%s)\n", size, name, r.WireBytes(), dataFormat(compression, chunked))
+	}
+	return true, nil
+}
+
+// errAborted is what sendData fails with when it aborted chunked data the
+// file couldn't be read for. The client can carry on with another request.
+var errAborted = errors.New("transfer aborted")
+
+// sendData copies size bytes of file data from r to the client, compressing
+// them on the way if the client asked for that.
+func (c *conn) sendData(r io.Reader, size uint64, compression messages.Compression, chunked bool, name string) error {
+	w, err := c.msgHandler.NewDataWriter(compression, chunked)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.CopyFrom(r, size)
+	var readErr *messages.ReadError
+	if chunked && errors.As(err, &readErr) {
+		c.server.logger().Printf("error reading %s: %v\n", name, readErr.Err)
+		if err := w.Abort(clientError(name, readErr.Err)); err != nil {
+			return err
+		}
+		return errAborted
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	if compression != messages.Compression_NONE || chunked {
+		c.server.logger().Printf("Sent %d bytes of %s as %d (%s)\n", size, name, w.WireBytes(), dataFormat(compression, chunked))
+	}
+	return nil
+}
+
+// dataFormat describes how file data is sent, for the log.
+func dataFormat(compression messages.Compression, chunked bool) string {
+	if chunked {
+		return util.CompressionName(compression) + ", chunked"
+	}
+	return util.CompressionName(compression)
+}
+
+// rejectData answers an upload whose data couldn't be stored, but which was
+// received in full or aborted by the client, so the connection can still be
+// used. Unless it was aborted, the client sends its checksum as usual.
+func (c *conn) rejectData(request *messages.StorageRequest, err error) error {
+	s := c.server
+	var aborted *messages.AbortedError
+	if errors.As(err, &aborted) {
+		s.logger().Println("Client aborted upload of", request.GetFileName()+":", aborted.Message)
+		return c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_ABORTED, "Upload aborted"))
+	}
+
+	s.logger().Println("FAILED to store", request.GetFileName()+":", err)
+	if _, err := c.msgHandler.Receive(); err != nil && !errors.Is(err, messages.ErrMalformed) {
+		return fmt.Errorf("error receiving checksum: %w", err)
+	}
+	return c.msgHandler.SendResponse(refusal(request.GetFileName(), err))
+}
+
+// interrupted handles an upload that stopped partway through. If Shutdown cut
+// it short, the client is still expecting a reply once it has sent the rest,
+// and if the client stopped sending, it is told why in case it carries on.
+func (c *conn) interrupted(request *messages.StorageRequest, err error) error {
+	switch {
+	case c.server.shuttingDown():
+		c.notifyShutdown()
+	case errors.Is(err, messages.ErrStalled):
+		stall := c.server.Timeouts.Stall
+		resp := messages.Refusal(messages.ErrorCode_ABORTED, fmt.Sprintf("No data for %v, upload given up on.", stall))
+		resp.Details = map[string]string{"name": request.GetFileName(), "stall_timeout": strconv.Itoa(int(math.Ceil(stall.Seconds())))}
+		// The handler won't wait on the connection any more; the reply gets a
+		// moment of its own
+		c.netConn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.msgHandler.SendResponse(resp)
+	}
+	return fmt.Errorf("upload of %s interrupted: %w", request.GetFileName(), err)
+}
+
+// checkConflict refuses a StorageRequest up front if the file it names would
+// get in its way.
+func (c *conn) checkConflict(request *messages.StorageRequest) error {
+	if _, ok := messages.ConflictPolicy_name[int32(request.GetConflict())]; !ok {
+		return invalidRequest(fmt.Sprintf("unknown conflict policy %d", request.GetConflict()))
+	}
+
+	_, err := c.store.Stat(request.GetFileName())
+	switch {
+	case errors.Is(err, storage.ErrNotRegular):
+		if request.GetConflict() != messages.ConflictPolicy_KEEP_BOTH {
+			return err
+		}
+	case err == nil && request.GetConflict() == messages.ConflictPolicy_REJECT:
+		return &fs.PathError{Op: "store", Path: request.GetFileName(), Err: fs.ErrExist}
+	}
+	return nil
+}
+
+// finishStorage compares the checksum of the data written to w with the
+// client's, and if they match, commits it. w is aborted if it isn't
+// committed, unless the client went away before sending its checksum and the
+// upload can be resumed.
+func (c *conn) finishStorage(request *messages.StorageRequest, w storage.Writer, checksum []byte) error {
+	s := c.server
+	serverCheck := &messages.ChecksumVerification{Algorithm: request.GetHash(), Checksum: checksum}
+
+	// A malformed checksum doesn't match, but needn't cost the connection
+	clientCheckMsg, err := c.msgHandler.Receive()
+	if err != nil && !errors.Is(err, messages.ErrMalformed) {
+		w.Close()
+		return fmt.Errorf("error receiving checksum: %w", err)
+	}
+	clientCheck := clientCheckMsg.GetChecksum()
+
+	if !util.VerifyChecksum(serverCheck, clientCheck) {
+		w.Abort()
+		s.logger().Printf("FAILED to store %s. Invalid checksum: server %s, client %s\n", request.GetFileName(), util.FormatChecksum(serverCheck), util.FormatChecksum(clientCheck))
+		return c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_CHECKSUM_MISMATCH, "Checksum mismatch"))
+	}
+
+	name, version, err := w.Commit(storage.Attributes{
+		Mode:     request.GetMode(),
+		ModTime:  request.GetModTime(),
+		Checksum: serverCheck,
+		Conflict: request.GetConflict(),
+	})
+	if err != nil {
+		s.logger().Println("FAILED to store", request.GetFileName()+":", err)
+		return c.msgHandler.SendResponse(refusal(request.GetFileName(), err))
+	}
+
+	s.logger().Println("Successfully stored", name)
+	if s.Hooks.OnStored != nil {
+		s.Hooks.OnStored(c.userName(), name, request.GetSize())
+	}
+	if request.GetResume() {
+		return c.msgHandler.SendStoredResponse("File stored successfully", name, version)
+	}
+	return c.msgHandler.SendResponse(messages.OK("File stored successfully as " + name))
+}
+
+func (c *conn) handleRetrieval(request *messages.RetrievalRequest) error {
+	s := c.server
+	s.logger().Println("Attempting to retrieve", request.GetFileName())
+
+	if !c.allowed(auth.Read) {
+		return nil
+	}
+
+	file, info, err := c.store.Open(request.GetFileName(), request.GetVersion())
+	if err != nil {
+		return c.msgHandler.SendRetrievalResponse(refusal(request.GetFileName(), err), 0, messages.Compression_NONE, false, false)
+	}
+	defer file.Close()
+
+	length, err := util.RangeLength(info.Size, request.GetOffset(), request.GetLength())
+	if err != nil {
+		return c.msgHandler.SendRetrievalResponse(messages.Refusal(messages.ErrorCode_INVALID_REQUEST, err.Error()), 0, messages.Compression_NONE, false, false)
+	}
+
+	hash, err := util.NewHash(request.GetHash())
+	if err != nil {
+		return c.msgHandler.SendRetrievalResponse(messages.Refusal(messages.ErrorCode_UNSUPPORTED, err.Error()), 0, messages.Compression_NONE, false, false)
+	}
+
+	compression := supported(request.GetCompression())
+	rangeChecksum := request.GetRangeChecksum()
+	if err := c.msgHandler.SendRetrievalResponse(messages.OK("Ready to send"), length, compression, request.GetChunked(), rangeChecksum); err != nil {
+		return err
+	}
+
+	// Unless the client only wants the range checked, the checksum covers
+	// the whole file, so hash around the requested range
+	skipped := hash
+	if rangeChecksum {
+		skipped = nil
+	}
+	if err := skip(file, int64(request.GetOffset()), skipped); err != nil {
+		return fmt.Errorf("error reading %s: %w", request.GetFileName(), err)
+	}
+	if err := c.sendData(io.TeeReader(file, hash), length, compression, request.GetChunked(), request.GetFileName()); err != nil {
+		if err == errAborted {
+			return nil
+		}
+		return fmt.Errorf("error sending %s: %w", request.GetFileName(), err)
+	}
+	if !rangeChecksum {
+		if _, err := io.Copy(hash, file); err != nil {
+			return fmt.Errorf("error reading %s: %w", request.GetFileName(), err)
+		}
+	}
+
+	if s.Hooks.OnRetrieved != nil {
+		s.Hooks.OnRetrieved(c.userName(), request.GetFileName(), length)
+	}
+	return c.msgHandler.SendChecksumVerification(request.GetHash(), hash.Sum(nil))
+}
+
+// skip moves r past its first n bytes, feeding them through h, or seeking
+// past them if h is nil and r can.
+func skip(r io.Reader, n int64, h hash.Hash) error {
+	if s, ok := r.(io.Seeker); ok && h == nil {
+		_, err := s.Seek(n, io.SeekStart)
+		return err
+	}
+	var w io.Writer = io.Discard
+	if h != nil {
+		w = h
+	}
+	_, err := io.CopyN(w, r, n)
+	return err
+}
+
+func (c *conn) handleList(request *messages.ListRequest) error {
+	s := c.server
+	s.logger().Println("Attempting to list", request.GetPrefix())
+
+	if !c.allowed(auth.Read) {
+		return nil
+	}
+
+	pageSize := int(request.GetPageSize())
+	if max := s.Limits.MaxPageSize; max > 0 && (pageSize <= 0 || pageSize > max) {
+		pageSize = max
+	}
+
+	infos, next, err := c.store.List(request.GetPrefix(), request.GetPageToken(), pageSize)
+	if err != nil {
+		return c.msgHandler.SendListResponse(refusal(request.GetPrefix(), err), nil, "")
+	}
+
+	entries := make([]*messages.FileEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = &messages.FileEntry{
+			Name:              info.Name,
+			Size:              info.Size,
+			ModTime:           info.ModTime.Unix(),
+			Checksum:          info.Checksum.GetChecksum(),
+			ChecksumAlgorithm: info.Checksum.GetAlgorithm(),
+			Mode:              info.Mode,
+			Version:           info.Version,
+		}
+	}
+
+	return c.msgHandler.SendListResponse(messages.OK(fmt.Sprintf("Found %d files", len(entries))), entries, next)
+}
+
+func (c *conn) handleDelete(request *messages.DeleteRequest) error {
+	s := c.server
+	s.logger().Println("Attempting to delete", request.GetFileName())
+
+	if !c.allowed(auth.Delete) {
+		return nil
+	}
+
+	if err := c.store.Remove(request.GetFileName()); err != nil {
+		return c.msgHandler.SendResponse(refusal(request.GetFileName(), err))
+	}
+
+	s.logger().Println("Successfully deleted", request.GetFileName())
+	if s.Hooks.OnDeleted != nil {
+		s.Hooks.OnDeleted(c.userName(), request.GetFileName())
+	}
+	return c.msgHandler.SendResponse(messages.OK("File deleted successfully"))
+}
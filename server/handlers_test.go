@@ -0,0 +1,33 @@
+package server
+
+import (
+	"io"
+	"testing"
+
+	"file-transfer/messages"
+	"file-transfer/storage"
+)
+
+func TestMissingVersionIsNotFound(t *testing.T) {
+	local, err := storage.NewLocal(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kind, s := range map[string]storage.Storage{"local": local, "memory": storage.NewMemory()} {
+		t.Run(kind, func(t *testing.T) {
+			w, err := s.Create("a.txt", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(w, "one")
+			if _, _, err := w.Commit(storage.Attributes{}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = s.Open("a.txt", 5)
+			if code := refusal("a.txt", err).GetCode(); code != messages.ErrorCode_NOT_FOUND {
+				t.Errorf("get of a missing version is refused as %v, want NOT_FOUND", code)
+			}
+		})
+	}
+}
@@ -1,118 +1,477 @@
-package main
+// Package server implements the file-transfer server. The server binaries are
+// thin wrappers around it, and it can be embedded in other programs; several
+// Servers can run in one process.
+package server
 
 import (
-	"crypto/md5"
-	"file-transfer/messages"
-	"file-transfer/util"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net"
-	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"file-transfer/auth"
+	"file-transfer/messages"
+	"file-transfer/storage"
 )
 
-func handleStorage(msgHandler *messages.MessageHandler, request *messages.StorageRequest) {
-	log.Println("Attempting to store", request.FileName)
-	file, err := os.OpenFile(request.FileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
-	if err != nil {
-		msgHandler.SendResponse(false, err.Error())
-		msgHandler.Close()
+// ErrServerClosed is returned by Serve once Shutdown has been called.
+var ErrServerClosed = errors.New("server closed")
+
+// Limits caps what clients may ask of the server. Zero values mean no limit
+// beyond the defaults.
+type Limits struct {
+	MaxFileSize    uint64 // Largest file a client may store
+	MaxPageSize    int    // Most LIST entries returned at once (util.MaxPageSize at most)
+	MaxMessageSize uint64 // Largest message a client may send (messages.DefaultMaxFrameSize by default, messages.MinMaxFrameSize at least)
+
+	// Most clients served at once, in all and from a single IP address.
+	// Clients over either limit are told the server is busy and to retry
+	// after RetryAfter (5 seconds by default).
+	MaxConns      int
+	MaxConnsPerIP int
+	RetryAfter    time.Duration
+}
+
+// defaultRetryAfter is how long busy clients are told to wait by default.
+const defaultRetryAfter = 5 * time.Second
+
+// maxBusyReplies is the most clients told the server is busy at once. Any
+// more are hung up on right away, so they don't run the server out of file
+// descriptors either.
+const maxBusyReplies = 64
+
+// Hooks are called as the server handles clients, e.g. to collect metrics or
+// audit access. Any of them may be nil. They run on the client's goroutine, so
+// they should return quickly. user is "" for clients of a server without
+// credentials.
+type Hooks struct {
+	OnConnect       func(addr net.Addr)
+	OnDisconnect    func(addr net.Addr)
+	OnAuthenticated func(addr net.Addr, user string)
+	OnStored        func(user string, name string, size uint64)
+	OnRetrieved     func(user string, name string, size uint64)
+	OnDeleted       func(user string, name string)
+}
+
+// Server serves file transfers out of a storage. The exported fields must be
+// set before calling Serve and not changed afterwards.
+type Server struct {
+	Storage     storage.Storage   // Where files are stored; required
+	Credentials *auth.Credentials // Tokens clients must authenticate with; nil lets everyone in
+	ACL         *auth.ACL         // What authenticated users may do; nil allows everything
+	Logger      *log.Logger       // nil logs to the standard logger; storage.Local has a Logger of its own
+	Limits      Limits
+	Timeouts    messages.Timeouts // How long to wait on clients; zero values wait forever
+	Hooks       Hooks
+
+	// How long the data of an interrupted upload is kept for its client to
+	// resume from, if the storage is a storage.Expirer; zero keeps it until
+	// the upload is tried again
+	PartialExpiry time.Duration
+
+	mu          sync.Mutex
+	listeners   map[net.Listener]struct{}
+	conns       map[*conn]struct{}
+	perIP       map[string]int // Number of conns from each IP address
+	busyReplies int
+	closing     bool
+	closed      chan struct{} // Closed by Shutdown, to stop expiring uploads
+}
+
+// Serve accepts connections on l and handles each client on its own
+// goroutine. It returns ErrServerClosed after Shutdown, or the listener's
+// error if it fails for any other reason.
+func (s *Server) Serve(l net.Listener) error {
+	if s.Storage == nil {
+		return errors.New("server has no storage")
+	}
+	if max := s.Limits.MaxMessageSize; max > 0 && max < messages.MinMaxFrameSize {
+		return fmt.Errorf("message size limit of %d bytes is below the %d bytes a chunk of file data needs", max, messages.MinMaxFrameSize)
+	}
+
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	if s.closed == nil {
+		s.closed = make(chan struct{})
+		go s.expireUploads(s.closed)
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	for {
+		netConn, err := l.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+
+			// Most likely out of file descriptors; give it a moment
+			s.logger().Println("Accept failed:", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		c, busy := s.newConn(netConn)
+		switch {
+		case busy != "":
+			go s.refuseBusy(netConn, busy)
+		case c == nil:
+			netConn.Close()
+		default:
+			go c.serve()
+		}
+	}
+}
+
+// Shutdown stops the server from accepting new connections and requests, and
+// waits for the transfers in progress to finish. Clients are told the server
+// is shutting down in place of the reply to their next request. If ctx is
+// done first, the remaining transfers are interrupted, their incomplete files
+// removed, and ctx's error is returned once they have stopped. Partial files
+// of resumable uploads are kept so that the clients can pick up where they
+// left off after a restart.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closing && s.closed != nil {
+		close(s.closed)
+	}
+	s.closing = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	s.mu.Unlock()
+
+	// Connections waiting for a request have nothing to finish
+	s.interrupt(false)
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for !s.drained() {
+		select {
+		case <-ctx.Done():
+			s.interrupt(true)
+			for !s.drained() {
+				<-ticker.C
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// expireUploads has the storage remove the data of uploads that have been
+// interrupted for longer than PartialExpiry every so often, until closed is.
+func (s *Server) expireUploads(closed <-chan struct{}) {
+	e, ok := s.Storage.(storage.Expirer)
+	if !ok || s.PartialExpiry <= 0 {
 		return
 	}
 
-	msgHandler.SendResponse(true, "Ready for data")
-	md5 := md5.New()
-	w := io.MultiWriter(file, md5)
-	io.CopyN(w, msgHandler, int64(request.Size)) /* Write and checksum as we go */
-	file.Close()
+	ticker := time.NewTicker(min(max(s.PartialExpiry/2, time.Second), time.Hour))
+	defer ticker.Stop()
+	for {
+		if err := e.Expire(time.Now().Add(-s.PartialExpiry)); err != nil {
+			s.logger().Println("error removing abandoned uploads:", err)
+		}
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// interrupt unblocks the connections waiting for a request, and with all,
+// those in the middle of one too.
+func (s *Server) interrupt(all bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		if all || c.idle {
+			c.msgHandler.Interrupt()
+		}
+	}
+}
+
+// drained reports whether every connection has been closed.
+func (s *Server) drained() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns) == 0
+}
 
-	serverCheck := md5.Sum(nil)
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// newConn starts serving a client, unless the server is shutting down or
+// busy. If it is busy, the reason is returned.
+func (s *Server) newConn(netConn net.Conn) (*conn, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return nil, ""
+	}
+	ip := remoteIP(netConn)
+	if max := s.Limits.MaxConns; max > 0 && len(s.conns) >= max {
+		return nil, "Server busy"
+	}
+	if max := s.Limits.MaxConnsPerIP; max > 0 && s.perIP[ip] >= max {
+		return nil, "Too many connections from " + ip
+	}
+	if s.conns == nil {
+		s.conns = make(map[*conn]struct{})
+		s.perIP = make(map[string]int)
+	}
+
+	c := &conn{
+		server:     s,
+		netConn:    netConn,
+		msgHandler: messages.NewMessageHandler(netConn),
+		ip:         ip,
+	}
+	if s.Limits.MaxMessageSize > 0 {
+		c.msgHandler.SetMaxFrameSize(s.Limits.MaxMessageSize)
+	}
+	c.msgHandler.SetTimeouts(s.Timeouts)
+	s.conns[c] = struct{}{}
+	s.perIP[ip]++
+	return c, ""
+}
 
-	clientCheckMsg, _ := msgHandler.Receive()
-	clientCheck := clientCheckMsg.GetChecksum().Checksum
+func (s *Server) removeConn(c *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, c)
+	if s.perIP[c.ip]--; s.perIP[c.ip] == 0 {
+		delete(s.perIP, c.ip)
+	}
+}
 
-	if util.VerifyChecksum(serverCheck, clientCheck) {
-		log.Println("Successfully stored file.")
-	} else {
-		log.Println("FAILED to store file. Invalid checksum.")
+// remoteIP returns the IP address a connection comes from.
+func remoteIP(netConn net.Conn) string {
+	addr := netConn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
 	}
+	return addr
 }
 
-func handleRetrieval(msgHandler *messages.MessageHandler, request *messages.RetrievalRequest) {
-	log.Println("Attempting to retrieve", request.FileName)
+// refuseBusy tells a client the server is too busy for it and when to try
+// again, in reply to its first request, then hangs up.
+func (s *Server) refuseBusy(netConn net.Conn, reason string) {
+	defer netConn.Close()
 
-	// Get file size and make sure it exists
-	info, err := os.Stat(request.FileName)
-	if err != nil {
-		log.Fatalln(err)
+	s.mu.Lock()
+	if s.busyReplies >= maxBusyReplies {
+		s.mu.Unlock()
+		return
 	}
+	s.busyReplies++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.busyReplies--
+		s.mu.Unlock()
+	}()
+
+	retryAfter := s.Limits.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultRetryAfter
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	s.logger().Println("Refusing connection", netConn.RemoteAddr().String()+":", reason)
+
+	// Hanging up with the request unread could reset the connection before
+	// the client reads the reply
+	m := messages.NewMessageHandler(netConn)
+	m.SetTimeouts(messages.Timeouts{Idle: time.Second, Message: time.Second})
+	if _, err := m.Receive(); err != nil && !errors.Is(err, messages.ErrMalformed) {
+		return
+	}
+	resp := messages.Refusal(messages.ErrorCode_UNAVAILABLE, fmt.Sprintf("%s, retry after %d seconds.", reason, seconds))
+	resp.Details = map[string]string{"retry_after": strconv.Itoa(seconds)}
+	m.SendResponse(resp)
+}
+
+func (s *Server) logger() *log.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return log.Default()
+}
 
-	msgHandler.SendRetrievalResponse(true, "Ready to send", uint64(info.Size()))
+// conn is a connection to a single client.
+type conn struct {
+	server     *Server
+	netConn    net.Conn
+	msgHandler *messages.MessageHandler
+	ip         string // Of the client, for the per-IP limit
 
-	file, _ := os.Open(request.FileName)
-	md5 := md5.New()
-	w := io.MultiWriter(msgHandler, md5)
-	io.CopyN(w, file, info.Size()) // Checksum and transfer file at same time
-	file.Close()
+	// Set once the client is let in; without credentials that is right away
+	user  *auth.User      // nil unless the client authenticated
+	store storage.Storage // the user's own directory, or the whole storage
 
-	checksum := md5.Sum(nil)
-	msgHandler.SendChecksumVerification(checksum)
+	idle bool // waiting for the next request; guarded by server.mu
 }
 
-func handleClient(msgHandler *messages.MessageHandler) {
-	defer msgHandler.Close()
+// setIdle records whether the connection is waiting for a request, and
+// reports false if the server is shutting down and it should stop instead.
+// Once the server is shutting down, only connections in the middle of a
+// request are left to finish.
+func (c *conn) setIdle(idle bool) bool {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	c.idle = idle
+	return !c.server.closing
+}
+
+func (c *conn) serve() {
+	s := c.server
+	addr := c.netConn.RemoteAddr()
+	defer func() {
+		c.msgHandler.Close()
+		s.removeConn(c)
+		if s.Hooks.OnDisconnect != nil {
+			s.Hooks.OnDisconnect(addr)
+		}
+	}()
+
+	s.logger().Println("Accepted connection", addr)
+	if s.Hooks.OnConnect != nil {
+		s.Hooks.OnConnect(addr)
+	}
+
+	// Without credentials every client is let in and shares the storage
+	if s.Credentials == nil {
+		c.store = s.Storage
+	}
 
 	for {
-		wrapper, err := msgHandler.Receive()
+		if !c.setIdle(true) {
+			c.notifyShutdown()
+			return
+		}
+		wrapper, err := c.msgHandler.Receive()
+		if !c.setIdle(false) {
+			// Interrupted by Shutdown, or too late to start anything new
+			c.notifyShutdown()
+			return
+		}
+		if errors.Is(err, messages.ErrMalformed) {
+			// It was read in full, so the connection can still be used
+			s.logger().Println("Refusing request:", err)
+			c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_INVALID_REQUEST, "Malformed request."))
+			continue
+		}
 		if err != nil {
-			log.Println(err)
+			c.receiveFailed(err)
+			return
 		}
 
-		switch msg := wrapper.Msg.(type) {
-		case *messages.Wrapper_StorageReq:
-			handleStorage(msgHandler, msg.StorageReq)
-			continue
-		case *messages.Wrapper_RetrievalReq:
-			handleRetrieval(msgHandler, msg.RetrievalReq)
-			continue
-		case nil:
-			log.Println("Received an empty message, terminating client")
+		if err := c.handle(wrapper); err != nil {
+			if err != errDisconnect {
+				s.logger().Println(err)
+			}
 			return
-		default:
-			log.Printf("Unexpected message type: %T", msg)
 		}
 	}
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Printf("Not enough arguments. Usage: %s port [download-dir]\n", os.Args[0])
-		os.Exit(1)
+// receiveFailed logs why the next request couldn't be received, telling the
+// client if it sent one too large to accept.
+func (c *conn) receiveFailed(err error) {
+	s := c.server
+	switch {
+	case err == io.EOF:
+		s.logger().Println("Client disconnected", c.netConn.RemoteAddr())
+	case errors.Is(err, messages.ErrTimeout):
+		s.logger().Println("Closing connection", c.netConn.RemoteAddr().String()+":", err)
+	case errors.Is(err, messages.ErrFrameTooLarge):
+		s.logger().Println("Refusing request:", err)
+		c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_INVALID_REQUEST, "Request too large."))
+	default:
+		s.logger().Println(err)
 	}
+}
 
-	port := os.Args[1]
-	listener, err := net.Listen("tcp", ":"+port)
-	if err != nil {
-		log.Fatalln(err.Error())
-		os.Exit(1)
-	}
-	defer listener.Close()
+// notifyShutdown tells the client the server is going away, in place of the
+// reply to whatever it asked for last.
+func (c *conn) notifyShutdown() {
+	c.server.logger().Println("Shutting down connection", c.netConn.RemoteAddr())
+	// Nothing more is read, and the client gets a second to take the notice
+	c.msgHandler.Interrupt()
+	c.netConn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_UNAVAILABLE, "Server shutting down."))
+}
+
+// errDisconnect ends a connection without anything worth logging
+var errDisconnect = errors.New("disconnect")
 
-	dir := "."
-	if len(os.Args) >= 3 {
-		dir = os.Args[2]
+// handle dispatches a single request. Requests that are refused get a reply
+// and leave the connection open; an error means the connection can't be used
+// any more and should be closed.
+func (c *conn) handle(wrapper *messages.Wrapper) error {
+	switch msg := wrapper.Msg.(type) {
+	case *messages.Wrapper_Hello:
+		return c.handleHello(msg.Hello)
+	case *messages.Wrapper_AuthReq:
+		return c.handleAuth(msg.AuthReq)
+	case nil:
+		// Sent by a newer client that knows of a request we don't
+		return c.unsupported(wrapper)
 	}
-	if err := os.Chdir(dir); err != nil {
-		log.Fatalln(err)
+	if c.store == nil {
+		c.server.logger().Println("Refusing unauthenticated request")
+		c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_UNAUTHENTICATED, "Authentication required."))
+		return errDisconnect
 	}
 
-	fmt.Println("Listening on port:", port)
-	fmt.Println("Download directory:", dir)
-	for {
-		if conn, err := listener.Accept(); err == nil {
-			log.Println("Accepted connection", conn.RemoteAddr())
-			handler := messages.NewMessageHandler(conn)
-			go handleClient(handler)
-		}
+	switch msg := wrapper.Msg.(type) {
+	case *messages.Wrapper_StorageReq:
+		return c.handleStorage(msg.StorageReq)
+	case *messages.Wrapper_RetrievalReq:
+		return c.handleRetrieval(msg.RetrievalReq)
+	case *messages.Wrapper_ListReq:
+		return c.handleList(msg.ListReq)
+	case *messages.Wrapper_DeleteReq:
+		return c.handleDelete(msg.DeleteReq)
+	default:
+		return c.unsupported(wrapper)
 	}
 }
+
+// unsupported refuses a request the server doesn't handle, keeping the
+// connection.
+func (c *conn) unsupported(wrapper *messages.Wrapper) error {
+	c.server.logger().Printf("Unsupported request: %T", wrapper.Msg)
+	return c.msgHandler.SendResponse(messages.Refusal(messages.ErrorCode_UNSUPPORTED, "Unsupported request."))
+}
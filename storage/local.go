@@ -0,0 +1,656 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"file-transfer/messages"
+	"file-transfer/util"
+)
+
+// Local keeps files in a directory on the local filesystem. Uploads are
+// written to hidden temporary files, synced and renamed into place, older
+// versions are kept as hidden files next to the current one, and the
+// checksums reported by List in hidden sidecar files.
+type Local struct {
+	// Where problems that don't fail the operation at hand, such as a
+	// directory that couldn't be synced, are logged; nil logs to the standard
+	// logger
+	Logger *log.Logger
+
+	root  *Root
+	dedup bool
+	mu    *sync.Mutex     // Serializes committing uploads and sweeping blobs
+	busy  map[string]bool // Partial and range files an upload is continuing from
+}
+
+// NewLocal opens the directory dir as a storage. With dedup, each distinct
+// content is stored once for every combination of permissions and
+// modification time it is stored with, with the files as hard links to it.
+func NewLocal(dir string, dedup bool) (*Local, error) {
+	if dedup && !util.CountsLinks {
+		return nil, errors.New("deduplication needs hard link counts, which this platform doesn't provide")
+	}
+
+	root, err := NewRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Local{root: root, dedup: dedup, mu: &sync.Mutex{}, busy: make(map[string]bool)}, nil
+}
+
+// Root returns the directory the files are kept in.
+func (l *Local) Root() *Root {
+	return l.root
+}
+
+// Sub returns the storage of a directory inside l, creating it if needed.
+// Deduplication only happens within each of them.
+func (l *Local) Sub(name string) (Storage, error) {
+	root, err := l.root.Sub(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Local{Logger: l.Logger, root: root, dedup: l.dedup, mu: l.mu, busy: l.busy}, nil
+}
+
+func (l *Local) logger() *log.Logger {
+	if l.Logger != nil {
+		return l.Logger
+	}
+	return log.Default()
+}
+
+// path resolves name for a file about to be written, making sure its
+// directory exists.
+func (l *Local) path(name string) (string, error) {
+	p, err := l.root.Resolve(name)
+	if err != nil {
+		return "", err
+	}
+
+	// Files uploaded as part of a directory tree arrive with subdirectories
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+func (l *Local) Create(name string, size uint64) (Writer, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nobody can get the file until it has been committed
+	file, err := util.CreateTemp(p)
+	if err != nil {
+		return nil, err
+	}
+	return &localWriter{l: l, file: file, path: p}, nil
+}
+
+// Resume picks up where the last attempt to upload name left off. The data
+// is kept in a hidden partial file until it is committed. Only one upload at
+// a time can continue from it; others fail with ErrBusy.
+func (l *Local) Resume(name string, size uint64, h hash.Hash) (Writer, uint64, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	partial := util.PartialName(p)
+	if err := l.claim(partial, p); err != nil {
+		return nil, 0, err
+	}
+	file, offset, err := util.OpenPartial(p, size, h)
+	if err != nil {
+		l.release(partial)
+		return nil, 0, err
+	}
+	return &localWriter{l: l, file: file, path: p, keep: true, claimed: partial, resumed: offset > 0}, offset, nil
+}
+
+// claim marks the hidden file an upload of p continues from as taken, failing
+// with ErrBusy if another upload has it.
+func (l *Local) claim(hidden string, p string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.busy[hidden] {
+		return &fs.PathError{Op: "store", Path: p, Err: ErrBusy}
+	}
+	l.busy[hidden] = true
+	return nil
+}
+
+// release lets other uploads continue from a hidden file again.
+func (l *Local) release(hidden string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.busy, hidden)
+}
+
+// WriteRange writes into a hidden file the ranges are put together in.
+func (l *Local) WriteRange(name string, size uint64, offset uint64) (io.WriteCloser, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := util.OpenRanges(p, size)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeWriter{Writer: io.NewOffsetWriter(file, int64(offset)), file: file}, nil
+}
+
+// Assemble picks up the file the ranges were written into. Like a partial
+// upload, it is kept if the client goes away before it is committed.
+func (l *Local) Assemble(name string, size uint64, h hash.Hash) (Writer, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := util.RangesName(p)
+	if err := l.claim(ranges, p); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(ranges, os.O_RDWR, 0)
+	if err != nil {
+		l.release(ranges)
+		return nil, &fs.PathError{Op: "assemble", Path: p, Err: errors.New("no ranges uploaded")}
+	}
+	info, err := file.Stat()
+	if err == nil && uint64(info.Size()) != size {
+		err = &fs.PathError{Op: "assemble", Path: p, Err: fmt.Errorf("ranges uploaded for %d bytes", info.Size())}
+	}
+	if err == nil {
+		_, err = io.Copy(h, file)
+	}
+	if err != nil {
+		file.Close()
+		l.release(ranges)
+		return nil, err
+	}
+	return &localWriter{l: l, file: file, path: p, keep: true, claimed: ranges}, nil
+}
+
+// Expire removes the partial and range files that haven't been written to
+// since before, throughout the directory, unless an upload is continuing
+// from them.
+func (l *Local) Expire(before time.Time) error {
+	return filepath.WalkDir(l.root.Dir(), func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir() && d.Name() == util.BlobDir:
+			return filepath.SkipDir
+		case !d.Type().IsRegular() || !util.IsPartial(p):
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			return nil
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.busy[p] {
+			return nil
+		}
+		l.logger().Println("Removing abandoned upload", p)
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	})
+}
+
+// Existing finds the content with the given SHA-256 among the blobs of a
+// deduplicating storage, preferably with the permissions and modification
+// time in attrs.
+func (l *Local) Existing(name string, size uint64, sum []byte, attrs Attributes, h hash.Hash) (Writer, error) {
+	if !l.dedup || len(sum) != sha256.Size {
+		return nil, nil
+	}
+	p, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := l.linkBlob(sum, size, attrs, p)
+	if file == nil || err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(h, file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &localWriter{l: l, file: file, path: p}, nil
+}
+
+// linkBlob makes a new temporary link next to p to a blob with the given
+// SHA-256, if there is one of the right size, and opens it.
+func (l *Local) linkBlob(sum []byte, size uint64, attrs Attributes, p string) (*os.File, error) {
+	// Don't let the blob be swept away before it's linked
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	blob := util.FindBlob(l.root.Dir(), sum, fs.FileMode(attrs.Mode), time.Unix(attrs.ModTime, 0))
+	if blob == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(blob)
+	if err != nil || uint64(info.Size()) != size {
+		return nil, nil
+	}
+
+	tempName, err := util.LinkTemp(blob, p)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(tempName)
+	if err != nil {
+		os.Remove(tempName)
+		return nil, err
+	}
+	return file, nil
+}
+
+func (l *Local) Open(name string, version uint32) (io.ReadCloser, *FileInfo, error) {
+	p, err := l.root.Resolve(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version != 0 {
+		p, err = util.VersionFile(p, version)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, &fs.PathError{Op: "open", Path: p, Err: ErrNotRegular}
+	}
+
+	return file, l.fileInfo(name, p, info), nil
+}
+
+func (l *Local) Stat(name string) (*FileInfo, error) {
+	p, err := l.root.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: ErrNotRegular}
+	}
+
+	fileInfo := l.fileInfo(name, p, info)
+	if latest, err := util.LatestVersion(p); err == nil && latest > 0 {
+		fileInfo.Version = latest + 1
+	}
+	return fileInfo, nil
+}
+
+// fileInfo describes the file at p, which clients call name.
+func (l *Local) fileInfo(name string, p string, info fs.FileInfo) *FileInfo {
+	return &FileInfo{
+		Name:     name,
+		Size:     uint64(info.Size()),
+		ModTime:  info.ModTime(),
+		Mode:     util.FileMode(info),
+		Checksum: util.LoadChecksum(p),
+	}
+}
+
+// List walks the directory for the regular files under it. Hidden files
+// (partial uploads, checksum sidecars, older versions) are left out.
+func (l *Local) List(prefix string, pageToken string, pageSize int) ([]*FileInfo, string, error) {
+	dir := l.root.Dir()
+
+	var names []string
+	versions := make(map[string]uint32) // Newest older version of each file
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			if fileName, n, ok := util.ParseVersionName(p); ok && n > versions[fileName] {
+				versions[fileName] = n
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	names, next := page(names, prefix, pageToken, pageSize)
+	infos := make([]*FileInfo, 0, len(names))
+	for _, name := range names {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Stat(p)
+		if err != nil {
+			// Removed while we were listing
+			continue
+		}
+
+		fileInfo := l.fileInfo(name, p, info)
+		if latest := versions[p]; latest > 0 {
+			fileInfo.Version = latest + 1
+		}
+		infos = append(infos, fileInfo)
+	}
+	return infos, next, nil
+}
+
+func (l *Local) Remove(name string) error {
+	p, err := l.root.Resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := util.RemoveFile(p); err != nil {
+		return err
+	}
+
+	l.sweepBlobs()
+	return nil
+}
+
+// commit renames the finished upload at tempName to p, dealing with a file
+// that is already there according to policy. It returns the path the upload
+// ended up at, and its version number if versions are kept of it.
+func (l *Local) commit(policy messages.ConflictPolicy, tempName string, p string) (string, uint32, error) {
+	// Only one upload at a time gets to pick a name or number a version
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	latest, err := util.LatestVersion(p)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if _, err := os.Lstat(p); err == nil {
+		switch policy {
+		case messages.ConflictPolicy_REJECT:
+			// Another client stored the same name in the meantime
+			return "", 0, &fs.PathError{Op: "store", Path: p, Err: fs.ErrExist}
+		case messages.ConflictPolicy_OVERWRITE:
+			// The content being replaced may not be linked anywhere else
+			defer l.sweepBlobsLocked()
+		case messages.ConflictPolicy_KEEP_BOTH:
+			p = util.FreeName(p)
+			latest = 0
+		case messages.ConflictPolicy_VERSION:
+			latest++
+			if err := os.Rename(p, util.VersionName(p, latest)); err != nil {
+				return "", 0, err
+			}
+		}
+	}
+
+	if err := os.Rename(tempName, p); err != nil {
+		if policy == messages.ConflictPolicy_VERSION && latest > 0 {
+			// Put the previous version back rather than lose the file
+			os.Rename(util.VersionName(p, latest), p)
+		}
+		return "", 0, err
+	}
+	if err := util.SyncDir(filepath.Dir(p)); err != nil {
+		l.logger().Println("error syncing directory:", err)
+	}
+
+	if latest == 0 && policy != messages.ConflictPolicy_VERSION {
+		return p, 0, nil
+	}
+	return p, latest + 1, nil
+}
+
+// intern gives the finished upload at tempName the permissions and
+// modification time in attrs, and turns it into a link to the blob with its
+// content and metadata, adding the blob if it is new. Metadata is only ever
+// applied to a file nothing else links to.
+func (l *Local) intern(tempName string, p string, attrs Attributes) error {
+	info, err := os.Stat(tempName)
+	if err != nil {
+		return err
+	}
+	if links, _ := util.LinkCount(info); links > 1 {
+		if hasMetadata(info, attrs) {
+			// Linked by Existing to a blob that already has it
+			return nil
+		}
+		if err := unshare(tempName, p); err != nil {
+			return err
+		}
+	}
+
+	if err := util.ApplyMetadata(tempName, attrs.Mode, attrs.ModTime); err != nil {
+		return err
+	}
+	info, err = os.Stat(tempName)
+	if err != nil {
+		return err
+	}
+	sum, err := util.HashFile(tempName, messages.HashAlgorithm_SHA256)
+	if err != nil {
+		return err
+	}
+	blob := util.BlobName(l.root.Dir(), sum, info.Mode(), info.ModTime())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = os.Stat(blob)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0777); err != nil {
+			return err
+		}
+		return os.Link(tempName, blob)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Keep the copy we already have instead
+	if err := os.Remove(tempName); err != nil {
+		return err
+	}
+	return os.Link(blob, tempName)
+}
+
+// hasMetadata reports whether the file info describes already has the
+// permissions and modification time in attrs, or the defaults for those
+// attrs leaves unset.
+func hasMetadata(info fs.FileInfo, attrs Attributes) bool {
+	return (attrs.Mode == 0 || info.Mode().Perm() == fs.FileMode(attrs.Mode).Perm()) &&
+		(attrs.ModTime == 0 || info.ModTime().Unix() == attrs.ModTime)
+}
+
+// unshare replaces the link at tempName, a temporary file for p, with a copy
+// of its own.
+func unshare(tempName string, p string) error {
+	src, err := os.Open(tempName)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := util.CreateTemp(p)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(dst.Name(), tempName)
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+	}
+	return err
+}
+
+// sweepBlobs removes the blobs no file links to any more, e.g. after a delete.
+func (l *Local) sweepBlobs() {
+	if !l.dedup {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweepBlobsLocked()
+}
+
+// sweepBlobsLocked is sweepBlobs for callers already holding mu.
+func (l *Local) sweepBlobsLocked() {
+	if !l.dedup {
+		return
+	}
+	if err := util.SweepBlobs(l.root.Dir()); err != nil {
+		l.logger().Println("error removing unused blobs:", err)
+	}
+}
+
+// rangeWriter writes one range of an upload in place.
+type rangeWriter struct {
+	io.Writer
+	file *os.File
+}
+
+func (w *rangeWriter) Close() error {
+	return w.file.Close()
+}
+
+// localWriter writes an upload into a hidden file next to path.
+type localWriter struct {
+	l       *Local
+	file    *os.File
+	path    string
+	keep    bool   // Keep the data of an interrupted upload to resume it later
+	claimed string // The hidden file claimed for the upload, if it continues one
+	resumed bool   // Started from data an earlier upload left behind
+}
+
+func (w *localWriter) Write(p []byte) (int, error) {
+	return w.file.Write(p)
+}
+
+func (w *localWriter) Commit(attrs Attributes) (string, uint32, error) {
+	l := w.l
+	tempName := w.file.Name()
+	defer w.release()
+
+	// Make sure the data is on disk before it shows up under the real name
+	err := w.file.Sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && w.resumed && attrs.Checksum != nil {
+		// The data left behind may have been changed since it was checksummed
+		err = w.verify(tempName, attrs.Checksum)
+	}
+	if err == nil && l.dedup {
+		err = l.intern(tempName, w.path, attrs)
+	} else if err == nil {
+		err = util.ApplyMetadata(tempName, attrs.Mode, attrs.ModTime)
+	}
+	var p string
+	var version uint32
+	if err == nil {
+		p, version, err = l.commit(attrs.Conflict, tempName, w.path)
+	}
+	if err != nil {
+		os.Remove(tempName)
+		l.sweepBlobs()
+		return "", 0, err
+	}
+
+	if attrs.Checksum != nil {
+		if err := util.SaveChecksum(p, attrs.Checksum); err != nil {
+			l.logger().Println("error recording checksum:", err)
+		}
+	}
+	return l.root.Name(p), version, nil
+}
+
+// verify checks that the data at tempName still has the checksum it was
+// received with.
+func (w *localWriter) verify(tempName string, check *messages.ChecksumVerification) error {
+	sum, err := util.HashFile(tempName, check.GetAlgorithm())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, check.GetChecksum()) {
+		return &fs.PathError{Op: "store", Path: w.path, Err: ErrCorrupt}
+	}
+	return nil
+}
+
+// release lets another upload continue from the hidden file w was writing.
+func (w *localWriter) release() {
+	if w.claimed != "" {
+		w.l.release(w.claimed)
+		w.claimed = ""
+	}
+}
+
+func (w *localWriter) Abort() error {
+	defer w.release()
+	w.file.Close()
+	return os.Remove(w.file.Name())
+}
+
+func (w *localWriter) Close() error {
+	if w.keep {
+		defer w.release()
+		return w.file.Close()
+	}
+	return w.Abort()
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+
+	"file-transfer/messages"
+)
+
+// Memory keeps files in memory, for tests and throwaway servers. It supports
+// every conflict policy, but not resuming or deduplicating uploads.
+type Memory struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+}
+
+// memFile is the current version of a file, along with the older ones.
+type memFile struct {
+	data     []byte
+	info     FileInfo
+	versions []*memFile // Oldest first
+}
+
+// NewMemory returns an empty in-memory storage.
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]*memFile)}
+}
+
+func (m *Memory) Create(name string, size uint64) (Writer, error) {
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	// size comes from the client, so don't allocate it all up front
+	return &memWriter{m: m, name: name, data: make([]byte, 0, min(size, 1<<20))}, nil
+}
+
+func (m *Memory) Open(name string, version uint32) (io.ReadCloser, *FileInfo, error) {
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, ok := m.files[name]
+	if !ok {
+		return nil, nil, notFound("open", name)
+	}
+	current := uint32(len(file.versions)) + 1
+	if version != 0 && version != current {
+		if version > current {
+			return nil, nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("no version %d: %w", version, fs.ErrNotExist)}
+		}
+		file = file.versions[version-1]
+	}
+
+	info := file.info
+	return io.NopCloser(bytes.NewReader(file.data)), &info, nil
+}
+
+func (m *Memory) Stat(name string) (*FileInfo, error) {
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, ok := m.files[name]
+	if !ok {
+		return nil, notFound("stat", name)
+	}
+	info := file.info
+	return &info, nil
+}
+
+func (m *Memory) List(prefix string, pageToken string, pageSize int) ([]*FileInfo, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+
+	names, next := page(names, prefix, pageToken, pageSize)
+	infos := make([]*FileInfo, len(names))
+	for i, name := range names {
+		info := m.files[name].info
+		infos[i] = &info
+	}
+	return infos, next, nil
+}
+
+func (m *Memory) Remove(name string) error {
+	name, err := CleanName(name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[name]; !ok {
+		return notFound("remove", name)
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// memWriter collects an upload until it is committed.
+type memWriter struct {
+	m    *Memory
+	name string
+	data []byte
+}
+
+func (w *memWriter) Write(p []byte) (int, error) {
+	w.data = append(w.data, p...)
+	return len(p), nil
+}
+
+func (w *memWriter) Commit(attrs Attributes) (string, uint32, error) {
+	m := w.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := w.name
+	file := &memFile{data: w.data}
+	if existing, ok := m.files[name]; ok {
+		switch attrs.Conflict {
+		case messages.ConflictPolicy_REJECT:
+			return "", 0, &fs.PathError{Op: "store", Path: name, Err: fs.ErrExist}
+		case messages.ConflictPolicy_OVERWRITE:
+			file.versions = existing.versions
+		case messages.ConflictPolicy_KEEP_BOTH:
+			name = freeName(name, func(name string) bool {
+				_, ok := m.files[name]
+				return ok
+			})
+		case messages.ConflictPolicy_VERSION:
+			old := *existing
+			old.versions = nil
+			old.info.Version = uint32(len(existing.versions)) + 1
+			file.versions = append(existing.versions, &old)
+		}
+	}
+
+	modTime := time.Now()
+	if attrs.ModTime != 0 {
+		modTime = time.Unix(attrs.ModTime, 0)
+	}
+	mode := attrs.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	file.info = FileInfo{
+		Name:     name,
+		Size:     uint64(len(w.data)),
+		ModTime:  modTime,
+		Mode:     mode,
+		Checksum: attrs.Checksum,
+	}
+	if len(file.versions) > 0 || attrs.Conflict == messages.ConflictPolicy_VERSION {
+		file.info.Version = uint32(len(file.versions)) + 1
+	}
+
+	m.files[name] = file
+	w.data = nil
+	return name, file.info.Version, nil
+}
+
+func (w *memWriter) Abort() error {
+	w.data = nil
+	return nil
+}
+
+func (w *memWriter) Close() error {
+	return w.Abort()
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrOutsideRoot = errors.New("outside of the storage directory")
+
+// Root is the directory a server stores its files in. Every client-supplied
+// file name is resolved against it, and names that would end up outside of
+// it, either lexically ("../x", "/etc/passwd") or by following a symlink, are
+// rejected, as are names of the hidden files kept next to the stored ones.
+type Root struct {
+	dir string
+}
+
+// NewRoot opens dir as a storage root. The directory must already exist.
+func NewRoot(dir string) (*Root, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve symlinks in the root itself so the containment checks below
+	// compare like with like
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(real)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s: not a directory", dir)
+	}
+
+	return &Root{dir: real}, nil
+}
+
+// Dir returns the absolute path of the storage directory.
+func (r *Root) Dir() string {
+	return r.dir
+}
+
+// Sub returns the root of a directory inside r, creating it if needed. Servers
+// use it to give each user a namespace of their own.
+func (r *Root) Sub(name string) (*Root, error) {
+	dir, err := r.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return NewRoot(dir)
+}
+
+// Resolve returns the absolute path that a client-supplied name refers to.
+// The name may contain subdirectories but must stay within the root.
+func (r *Root) Resolve(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == "." || clean == ".." ||
+		strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: %w", name, ErrOutsideRoot)
+	}
+	if Hidden(filepath.ToSlash(clean)) {
+		return "", fmt.Errorf("%s: %w", name, ErrHidden)
+	}
+
+	p := filepath.Join(r.dir, clean)
+	if err := r.checkSymlinks(p); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return p, nil
+}
+
+// Name returns the client-facing name of an absolute path inside the root.
+func (r *Root) Name(p string) string {
+	rel, err := filepath.Rel(r.dir, p)
+	if err != nil {
+		return p
+	}
+	return filepath.ToSlash(rel)
+}
+
+// checkSymlinks follows the symlinks along p, starting from the deepest part
+// of it that already exists, and makes sure they don't lead out of the root.
+func (r *Root) checkSymlinks(p string) error {
+	existing := p
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		existing = filepath.Dir(existing)
+	}
+
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// A dangling symlink; treat it as an escape rather than guess
+			// where it would end up once created
+			return ErrOutsideRoot
+		}
+		return err
+	}
+
+	if !r.contains(real) {
+		return ErrOutsideRoot
+	}
+	return nil
+}
+
+func (r *Root) contains(p string) bool {
+	return p == r.dir || strings.HasPrefix(p, r.dir+string(filepath.Separator))
+}
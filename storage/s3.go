@@ -0,0 +1,395 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"file-transfer/messages"
+	"file-transfer/util"
+)
+
+// S3Config says where an S3 storage keeps its files.
+type S3Config struct {
+	Endpoint  string // host[:port] of the service, e.g. s3.amazonaws.com
+	Bucket    string // Must exist already
+	Prefix    string // Key prefix for the files; "" for the whole bucket
+	AccessKey string
+	SecretKey string
+	Insecure  bool // Plain HTTP, e.g. for a MinIO server on localhost
+}
+
+// S3 keeps files as objects in a bucket of Amazon S3 or a compatible service
+// such as MinIO. Uploads are written to a hidden temporary object and copied
+// into place once they are committed. Older versions are kept as hidden
+// objects next to the current one, and the permissions, modification time,
+// checksum and version number in user metadata.
+//
+// Conflicts are resolved within a single S3 at a time; servers sharing a
+// bucket should each use a prefix of their own.
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+
+	mu sync.Mutex // Serializes committing uploads
+}
+
+// Keys of the user metadata recorded with each object
+const (
+	metaMode     = "Mode"
+	metaModTime  = "Mtime"
+	metaChecksum = "Checksum"
+	metaVersion  = "Version"
+)
+
+// errAborted is what an upload that was given up on fails with.
+var errAborted = errors.New("upload aborted")
+
+// NewS3 connects to the service described by config and checks the bucket is
+// there.
+func NewS3(config S3Config) (*S3, error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: !config.Insecure,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := client.BucketExists(context.Background(), config.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("bucket %s does not exist", config.Bucket)
+	}
+
+	prefix := strings.Trim(config.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3{client: client, bucket: config.Bucket, prefix: prefix}, nil
+}
+
+// OpenLocation opens a storage given as either a local directory or an S3
+// bucket written as s3://bucket[/prefix]. The rest of the S3 settings come
+// from s3.
+func OpenLocation(location string, dedup bool, s3 S3Config) (Storage, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		return NewLocal(location, dedup)
+	}
+	if dedup {
+		return nil, errors.New("deduplication needs local storage")
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	s3.Bucket = u.Host
+	s3.Prefix = u.Path
+	return NewS3(s3)
+}
+
+func (s *S3) key(name string) string {
+	return s.prefix + name
+}
+
+// s3Error turns an error from the service into one about name.
+func s3Error(op string, name string, err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return notFound(op, name)
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (s *S3) Create(name string, size uint64) (Writer, error) {
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	temp := s.key(".uploads/" + hex.EncodeToString(random))
+
+	// The object is streamed as the data arrives
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.client.PutObject(context.Background(), s.bucket, temp, pr, int64(size), minio.PutObjectOptions{})
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	return &s3Writer{s: s, name: name, temp: temp, pw: pw, done: done}, nil
+}
+
+func (s *S3) Open(name string, version uint32) (io.ReadCloser, *FileInfo, error) {
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := s.key(name)
+	if version != 0 {
+		info, err := s.Stat(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		current := info.Version
+		if current == 0 {
+			current = 1
+		}
+		if version > current {
+			return nil, nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("no version %d: %w", version, fs.ErrNotExist)}
+		}
+		if version < current {
+			key = s.key(versionName(name, version))
+		}
+	}
+
+	object, err := s.client.GetObject(context.Background(), s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, s3Error("open", name, err)
+	}
+	objectInfo, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, nil, s3Error("open", name, err)
+	}
+	return object, s.fileInfo(name, objectInfo), nil
+}
+
+func (s *S3) Stat(name string) (*FileInfo, error) {
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	objectInfo, err := s.client.StatObject(context.Background(), s.bucket, s.key(name), minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error("stat", name, err)
+	}
+	return s.fileInfo(name, objectInfo), nil
+}
+
+// fileInfo describes the object for the file name.
+func (s *S3) fileInfo(name string, objectInfo minio.ObjectInfo) *FileInfo {
+	info := &FileInfo{
+		Name:    name,
+		Size:    uint64(objectInfo.Size),
+		ModTime: objectInfo.LastModified,
+		Mode:    0644,
+	}
+
+	meta := objectInfo.UserMetadata
+	if mode, err := strconv.ParseUint(meta[metaMode], 8, 32); err == nil {
+		info.Mode = uint32(mode)
+	}
+	if modTime, err := strconv.ParseInt(meta[metaModTime], 10, 64); err == nil {
+		info.ModTime = time.Unix(modTime, 0)
+	}
+	info.Checksum = util.ParseChecksum(meta[metaChecksum])
+	if version, err := strconv.ParseUint(meta[metaVersion], 10, 32); err == nil {
+		info.Version = uint32(version)
+	}
+	return info
+}
+
+// List pages through the keys, which the service returns sorted, then looks
+// up the metadata of the files on the page.
+func (s *S3) List(prefix string, pageToken string, pageSize int) ([]*FileInfo, string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := minio.ListObjectsOptions{Prefix: s.key(prefix), Recursive: true}
+	if pageToken != "" {
+		opts.StartAfter = s.key(pageToken)
+	}
+
+	// One more than fits on the page tells whether there is another one
+	var names []string
+	for object := range s.client.ListObjects(ctx, s.bucket, opts) {
+		if object.Err != nil {
+			return nil, "", object.Err
+		}
+		name := strings.TrimPrefix(object.Key, s.prefix)
+		if Hidden(name) {
+			continue
+		}
+		names = append(names, name)
+		if len(names) > pageLimit(pageSize) {
+			break
+		}
+	}
+
+	names, next := page(names, prefix, pageToken, pageSize)
+	infos := make([]*FileInfo, 0, len(names))
+	for _, name := range names {
+		info, err := s.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed while we were listing
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		infos = append(infos, info)
+	}
+	return infos, next, nil
+}
+
+func (s *S3) Remove(name string) error {
+	info, err := s.Stat(name)
+	if err != nil {
+		return err
+	}
+	name = info.Name
+
+	ctx := context.Background()
+	if err := s.client.RemoveObject(ctx, s.bucket, s.key(name), minio.RemoveObjectOptions{}); err != nil {
+		return s3Error("remove", name, err)
+	}
+	for n := uint32(1); n < info.Version; n++ {
+		if err := s.client.RemoveObject(ctx, s.bucket, s.key(versionName(name, n)), minio.RemoveObjectOptions{}); err != nil {
+			return s3Error("remove", name, err)
+		}
+	}
+	return nil
+}
+
+// copy copies the object at src to dst, replacing its metadata with meta.
+// Objects over 5 GiB have to be copied in parts.
+func (s *S3) copy(src string, dst string, size uint64, meta map[string]string) error {
+	dstOpts := minio.CopyDestOptions{Bucket: s.bucket, Object: dst, UserMetadata: meta, ReplaceMetadata: true}
+	srcOpts := minio.CopySrcOptions{Bucket: s.bucket, Object: src}
+
+	var err error
+	if size <= 5<<30 {
+		_, err = s.client.CopyObject(context.Background(), dstOpts, srcOpts)
+	} else {
+		_, err = s.client.ComposeObject(context.Background(), dstOpts, srcOpts)
+	}
+	return err
+}
+
+// metadata is the user metadata recorded for info.
+func metadata(info *FileInfo) map[string]string {
+	meta := map[string]string{
+		metaMode:    strconv.FormatUint(uint64(info.Mode), 8),
+		metaModTime: strconv.FormatInt(info.ModTime.Unix(), 10),
+		metaVersion: strconv.FormatUint(uint64(info.Version), 10),
+	}
+	if info.Checksum != nil {
+		meta[metaChecksum] = util.FormatChecksum(info.Checksum)
+	}
+	return meta
+}
+
+// s3Writer streams an upload into a temporary object.
+type s3Writer struct {
+	s    *S3
+	name string
+	temp string // Key of the temporary object
+	pw   *io.PipeWriter
+	done chan error // Result of the upload
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *s3Writer) Commit(attrs Attributes) (string, uint32, error) {
+	s := w.s
+	w.pw.Close()
+	if err := <-w.done; err != nil {
+		return "", 0, s3Error("store", w.name, err)
+	}
+	defer s.client.RemoveObject(context.Background(), s.bucket, w.temp, minio.RemoveObjectOptions{})
+
+	// Only one upload at a time gets to pick a name or number a version
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := w.name
+	version := uint32(0)
+	existing, err := s.Stat(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if attrs.Conflict == messages.ConflictPolicy_VERSION {
+			version = 1
+		}
+	case err != nil:
+		return "", 0, err
+	case attrs.Conflict == messages.ConflictPolicy_REJECT:
+		return "", 0, &fs.PathError{Op: "store", Path: name, Err: fs.ErrExist}
+	case attrs.Conflict == messages.ConflictPolicy_OVERWRITE:
+		version = existing.Version
+	case attrs.Conflict == messages.ConflictPolicy_KEEP_BOTH:
+		name = freeName(name, func(name string) bool {
+			_, err := s.Stat(name)
+			return err == nil
+		})
+	case attrs.Conflict == messages.ConflictPolicy_VERSION:
+		if existing.Version == 0 {
+			existing.Version = 1
+		}
+		if err := s.copy(s.key(name), s.key(versionName(name, existing.Version)), existing.Size, metadata(existing)); err != nil {
+			return "", 0, s3Error("store", name, err)
+		}
+		version = existing.Version + 1
+	}
+
+	objectInfo, err := s.client.StatObject(context.Background(), s.bucket, w.temp, minio.StatObjectOptions{})
+	if err != nil {
+		return "", 0, s3Error("store", name, err)
+	}
+	info := &FileInfo{
+		Name:     name,
+		Size:     uint64(objectInfo.Size),
+		ModTime:  objectInfo.LastModified,
+		Mode:     0644,
+		Checksum: attrs.Checksum,
+		Version:  version,
+	}
+	if attrs.Mode != 0 {
+		info.Mode = attrs.Mode
+	}
+	if attrs.ModTime != 0 {
+		info.ModTime = time.Unix(attrs.ModTime, 0)
+	}
+	if err := s.copy(w.temp, s.key(name), info.Size, metadata(info)); err != nil {
+		return "", 0, s3Error("store", name, err)
+	}
+	return name, version, nil
+}
+
+func (w *s3Writer) Abort() error {
+	w.pw.CloseWithError(errAborted)
+	if err := <-w.done; err != nil {
+		return nil
+	}
+
+	// All the data had arrived already
+	return w.s.client.RemoveObject(context.Background(), w.s.bucket, w.temp, minio.RemoveObjectOptions{})
+}
+
+func (w *s3Writer) Close() error {
+	return w.Abort()
+}
@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"file-transfer/messages"
+	"file-transfer/util"
+)
+
+// ErrNotRegular is returned for names that refer to something other than a
+// file, such as a directory.
+var ErrNotRegular = util.ErrNotRegular
+
+// ErrHidden is returned for client-supplied names with a component starting
+// with a dot, which are reserved for the files a storage keeps for itself.
+var ErrHidden = errors.New("names starting with a dot are reserved")
+
+// ErrBusy is returned for an upload that would continue from the same kept
+// data as another one still in progress.
+var ErrBusy = errors.New("another upload of the file is in progress")
+
+// ErrCorrupt is returned by Commit when the data kept for an upload no longer
+// matches the checksum it was received with.
+var ErrCorrupt = errors.New("stored data doesn't match its checksum")
+
+// Storage is where a server keeps its files. Names are slash-separated paths
+// chosen by clients; implementations reject names that would lead outside of
+// the storage. Errors about a particular file are *fs.PathErrors, so that
+// errors.Is(err, fs.ErrNotExist) and friends work whatever the storage.
+type Storage interface {
+	// Create starts a new file of the given size. Nothing shows up under name
+	// until the returned Writer is committed.
+	Create(name string, size uint64) (Writer, error)
+
+	// Open returns the contents of the given version of a file (0 for the
+	// current one), along with its description.
+	Open(name string, version uint32) (io.ReadCloser, *FileInfo, error)
+
+	// Stat describes the current version of a file.
+	Stat(name string) (*FileInfo, error)
+
+	// List returns one page of the files whose names start with prefix,
+	// sorted by name, starting after pageToken. The second return value is
+	// the token for the next page, or "" on the last one.
+	List(prefix string, pageToken string, pageSize int) ([]*FileInfo, string, error)
+
+	// Remove deletes a file along with its older versions.
+	Remove(name string) error
+}
+
+// Writer receives the data of a file being stored.
+type Writer interface {
+	io.Writer
+
+	// Commit stores what was written under the name the Writer was created
+	// for, resolving a clash with an existing file according to
+	// attrs.Conflict. It returns the name the file ended up under and its
+	// version number, or 0 if no older versions are kept of it.
+	Commit(attrs Attributes) (string, uint32, error)
+
+	// Abort throws away what was written.
+	Abort() error
+
+	// Close gives up on a transfer that was interrupted. Unlike Abort, a
+	// Writer from Resumer.Resume keeps the data so the transfer can be
+	// continued later.
+	Close() error
+}
+
+// Attributes are recorded with a file as it is committed.
+type Attributes struct {
+	Mode     uint32                         // Permission bits; 0 keeps the default
+	ModTime  int64                          // Unix seconds; 0 keeps the time of the commit
+	Checksum *messages.ChecksumVerification // Reported by List
+	Conflict messages.ConflictPolicy
+}
+
+// FileInfo describes a stored file.
+type FileInfo struct {
+	Name     string
+	Size     uint64
+	ModTime  time.Time
+	Mode     uint32                         // Permission bits
+	Checksum *messages.ChecksumVerification // nil if unknown
+	Version  uint32                         // Current version number; 0 if no older versions are kept
+}
+
+// Resumer is implemented by storages that keep the data of an interrupted
+// upload so that it can be continued.
+type Resumer interface {
+	// Resume is Create for an upload that may continue an earlier one. The
+	// data already received is fed through h, and its length returned; the
+	// Writer takes the rest.
+	Resume(name string, size uint64, h hash.Hash) (Writer, uint64, error)
+}
+
+// Deduplicator is implemented by storages that keep identical content once.
+type Deduplicator interface {
+	// Existing is Create for an upload whose content the storage may already
+	// hold, going by its SHA-256. If it does, the content is fed through h and
+	// the returned Writer already holds all of it; otherwise the Writer is
+	// nil. attrs are those the upload will be committed with, so a copy of
+	// the content that already has them can be picked.
+	Existing(name string, size uint64, sha256 []byte, attrs Attributes, h hash.Hash) (Writer, error)
+}
+
+// Assembler is implemented by storages that can put a file together from
+// ranges uploaded separately, e.g. over several connections at once.
+type Assembler interface {
+	// WriteRange returns a writer for the data of an upload of name, a file
+	// of the given size, from offset onwards. Ranges of the same upload can be
+	// written at the same time.
+	WriteRange(name string, size uint64, offset uint64) (io.WriteCloser, error)
+
+	// Assemble is Create for an upload whose ranges have all been written.
+	// The data is fed through h, and the returned Writer already holds all
+	// of it.
+	Assemble(name string, size uint64, h hash.Hash) (Writer, error)
+}
+
+// Expirer is implemented by storages that keep what interrupted uploads leave
+// behind, so it isn't kept forever when their clients never come back.
+type Expirer interface {
+	// Expire removes the data kept for uploads that was last written to
+	// before the given time, other than that of uploads in progress.
+	Expire(before time.Time) error
+}
+
+// Sub returns the part of s under the directory name, as a storage of its
+// own. Servers use it to give each user a namespace.
+func Sub(s Storage, name string) (Storage, error) {
+	if sub, ok := s.(interface {
+		Sub(name string) (Storage, error)
+	}); ok {
+		return sub.Sub(name)
+	}
+
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &prefixed{Storage: s, prefix: name + "/"}, nil
+}
+
+// CleanName returns the canonical form of a client-supplied name, or an
+// error if it would lead outside of the storage or to a hidden file.
+func CleanName(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%s: %w", name, ErrOutsideRoot)
+	}
+	if Hidden(clean) {
+		return "", fmt.Errorf("%s: %w", name, ErrHidden)
+	}
+	return clean, nil
+}
+
+// Hidden reports whether a name has a component starting with a dot. Such
+// files (partial uploads, older versions) are left out of listings, and
+// clients can't refer to them.
+func Hidden(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.Contains(name, "/.")
+}
+
+// page picks one page out of names: those starting with prefix and sorting
+// after pageToken. It also returns the token for the next page.
+func page(names []string, prefix string, pageToken string, pageSize int) ([]string, string) {
+	sort.Strings(names)
+
+	var selected []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && name > pageToken && !Hidden(name) {
+			selected = append(selected, name)
+		}
+	}
+
+	pageSize = pageLimit(pageSize)
+	if len(selected) > pageSize {
+		selected = selected[:pageSize]
+		return selected, selected[pageSize-1]
+	}
+	return selected, ""
+}
+
+// pageLimit is the number of entries List returns for the requested page size.
+func pageLimit(pageSize int) int {
+	if pageSize <= 0 {
+		return util.DefaultPageSize
+	} else if pageSize > util.MaxPageSize {
+		return util.MaxPageSize
+	}
+	return pageSize
+}
+
+// notFound is the error for a name that isn't there.
+func notFound(op string, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// versionName is where storages other than the local one keep version n of
+// name once a newer one has been stored.
+func versionName(name string, n uint32) string {
+	dir, base := path.Split(name)
+	return fmt.Sprintf("%s.%s.v%d", dir, base, n)
+}
+
+// freeName returns the first name of the form "report (1).pdf" that isn't
+// taken according to exists.
+func freeName(name string, exists func(string) bool) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if !exists(candidate) {
+			return candidate
+		}
+	}
+}
+
+// prefixed is the part of a storage under a directory.
+type prefixed struct {
+	Storage
+	prefix string
+}
+
+func (p *prefixed) name(name string) (string, error) {
+	clean, err := CleanName(name)
+	if err != nil {
+		return "", err
+	}
+	return p.prefix + clean, nil
+}
+
+// strip turns a name in the underlying storage back into one relative to p.
+func (p *prefixed) strip(info *FileInfo) *FileInfo {
+	info.Name = strings.TrimPrefix(info.Name, p.prefix)
+	return info
+}
+
+// stripErr does the same for the name in an error.
+func (p *prefixed) stripErr(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		pathErr.Path = strings.TrimPrefix(pathErr.Path, p.prefix)
+	}
+	return err
+}
+
+func (p *prefixed) Create(name string, size uint64) (Writer, error) {
+	full, err := p.name(name)
+	if err != nil {
+		return nil, err
+	}
+	w, err := p.Storage.Create(full, size)
+	if err != nil {
+		return nil, p.stripErr(err)
+	}
+	return &prefixedWriter{Writer: w, p: p}, nil
+}
+
+func (p *prefixed) Open(name string, version uint32) (io.ReadCloser, *FileInfo, error) {
+	full, err := p.name(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, info, err := p.Storage.Open(full, version)
+	if err != nil {
+		return nil, nil, p.stripErr(err)
+	}
+	return r, p.strip(info), nil
+}
+
+func (p *prefixed) Stat(name string) (*FileInfo, error) {
+	full, err := p.name(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := p.Storage.Stat(full)
+	if err != nil {
+		return nil, p.stripErr(err)
+	}
+	return p.strip(info), nil
+}
+
+func (p *prefixed) List(prefix string, pageToken string, pageSize int) ([]*FileInfo, string, error) {
+	if pageToken != "" {
+		pageToken = p.prefix + pageToken
+	}
+	infos, next, err := p.Storage.List(p.prefix+prefix, pageToken, pageSize)
+	if err != nil {
+		return nil, "", p.stripErr(err)
+	}
+	for _, info := range infos {
+		p.strip(info)
+	}
+	return infos, strings.TrimPrefix(next, p.prefix), nil
+}
+
+func (p *prefixed) Remove(name string) error {
+	full, err := p.name(name)
+	if err != nil {
+		return err
+	}
+	return p.stripErr(p.Storage.Remove(full))
+}
+
+type prefixedWriter struct {
+	Writer
+	p *prefixed
+}
+
+func (w *prefixedWriter) Commit(attrs Attributes) (string, uint32, error) {
+	name, version, err := w.Writer.Commit(attrs)
+	if err != nil {
+		return "", 0, w.p.stripErr(err)
+	}
+	return strings.TrimPrefix(name, w.p.prefix), version, nil
+}
@@ -0,0 +1,389 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"file-transfer/messages"
+	"file-transfer/util"
+)
+
+// storages returns a fresh storage of each kind the contract is checked
+// against, including the parts of them Sub hands out.
+func storages(t *testing.T) map[string]Storage {
+	t.Helper()
+
+	localSub, err := Sub(mustLocal(t), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	memSub, err := Sub(NewMemory(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]Storage{
+		"local":      mustLocal(t),
+		"local/sub":  localSub,
+		"memory":     NewMemory(),
+		"memory/sub": memSub,
+	}
+}
+
+// store commits data under name, returning what Commit does.
+func store(t *testing.T, s Storage, name string, data string, conflict messages.ConflictPolicy) (string, uint32, error) {
+	t.Helper()
+
+	w, err := s.Create(name, uint64(len(data)))
+	if err != nil {
+		t.Fatalf("Create(%q): %v", name, err)
+	}
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatalf("Write(%q): %v", name, err)
+	}
+	return w.Commit(Attributes{Mode: 0640, ModTime: 1700000000, Conflict: conflict})
+}
+
+// read returns the contents of a version of name.
+func read(t *testing.T, s Storage, name string, version uint32) string {
+	t.Helper()
+
+	r, _, err := s.Open(name, version)
+	if err != nil {
+		t.Fatalf("Open(%q, %d): %v", name, version, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading %q: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestStoreAndOpen(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			name, _, err := store(t, s, "dir/a.txt", "hello", messages.ConflictPolicy_REJECT)
+			if err != nil || name != "dir/a.txt" {
+				t.Fatalf("Commit = %q, %v; want dir/a.txt", name, err)
+			}
+			if got := read(t, s, "dir/a.txt", 0); got != "hello" {
+				t.Errorf("read %q, want hello", got)
+			}
+
+			info, err := s.Stat("dir/a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Name != "dir/a.txt" || info.Size != 5 || info.Mode != 0640 || !info.ModTime.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("Stat = %+v", info)
+			}
+		})
+	}
+}
+
+func TestAbortedUploadIsNotStored(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			w, err := s.Create("a.txt", 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(w, "hello")
+			if err := w.Abort(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Stat("a.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat after Abort = %v, want fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestCreateDoesNotTrustSize(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			// Sizes come from clients, and may be anything
+			w, err := s.Create("a.txt", 1<<62)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Abort()
+		})
+	}
+}
+
+func TestConflictPolicies(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			store(t, s, "a.txt", "one", messages.ConflictPolicy_REJECT)
+
+			if _, _, err := store(t, s, "a.txt", "two", messages.ConflictPolicy_REJECT); !errors.Is(err, fs.ErrExist) {
+				t.Errorf("REJECT = %v, want fs.ErrExist", err)
+			}
+
+			if _, _, err := store(t, s, "a.txt", "two", messages.ConflictPolicy_OVERWRITE); err != nil {
+				t.Fatal(err)
+			}
+			if got := read(t, s, "a.txt", 0); got != "two" {
+				t.Errorf("after OVERWRITE read %q, want two", got)
+			}
+
+			name, _, err := store(t, s, "a.txt", "three", messages.ConflictPolicy_KEEP_BOTH)
+			if err != nil || name != "a (1).txt" {
+				t.Errorf("KEEP_BOTH = %q, %v; want a (1).txt", name, err)
+			}
+			if got := read(t, s, "a.txt", 0); got != "two" {
+				t.Errorf("after KEEP_BOTH read %q, want two", got)
+			}
+
+			_, version, err := store(t, s, "a.txt", "four", messages.ConflictPolicy_VERSION)
+			if err != nil || version != 2 {
+				t.Fatalf("VERSION = %d, %v; want version 2", version, err)
+			}
+			if got := read(t, s, "a.txt", 1); got != "two" {
+				t.Errorf("version 1 is %q, want two", got)
+			}
+			if got := read(t, s, "a.txt", 0); got != "four" {
+				t.Errorf("current version is %q, want four", got)
+			}
+		})
+	}
+}
+
+func TestMissingVersion(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			store(t, s, "a.txt", "one", messages.ConflictPolicy_REJECT)
+			if _, _, err := s.Open("a.txt", 5); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Open of a missing version = %v, want fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestListPages(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			for _, name := range []string{"b", "a", "dir/c", "dir/d", "e"} {
+				store(t, s, name, name, messages.ConflictPolicy_REJECT)
+			}
+			// Older versions are hidden files, which aren't listed
+			store(t, s, "e", "e2", messages.ConflictPolicy_VERSION)
+
+			var names []string
+			token := ""
+			for pages := 0; ; pages++ {
+				infos, next, err := s.List("", token, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(infos) > 2 || pages > 3 {
+					t.Fatalf("page of %d entries after %d pages", len(infos), pages)
+				}
+				for _, info := range infos {
+					names = append(names, info.Name)
+				}
+				if next == "" {
+					break
+				}
+				token = next
+			}
+			if got, want := names, []string{"a", "b", "dir/c", "dir/d", "e"}; !slices.Equal(got, want) {
+				t.Errorf("listed %q, want %q", got, want)
+			}
+
+			infos, _, err := s.List("dir/", "", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(infos) != 2 || infos[0].Name != "dir/c" || infos[1].Name != "dir/d" {
+				t.Errorf("List(dir/) = %d entries", len(infos))
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			store(t, s, "a.txt", "one", messages.ConflictPolicy_REJECT)
+			store(t, s, "a.txt", "two", messages.ConflictPolicy_VERSION)
+
+			if err := s.Remove("a.txt"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Stat("a.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat after Remove = %v, want fs.ErrNotExist", err)
+			}
+			if _, _, err := s.Open("a.txt", 1); err == nil {
+				t.Error("older version still there after Remove")
+			}
+
+			var pathErr *fs.PathError
+			if err := s.Remove("a.txt"); !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &pathErr) {
+				t.Errorf("second Remove = %v, want a *fs.PathError for fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestRemoveDirectory(t *testing.T) {
+	l := mustLocal(t)
+	store(t, l, "dir/a.txt", "a", messages.ConflictPolicy_REJECT)
+
+	// Like a get of it, so both are refused the same way
+	if err := l.Remove("dir"); !errors.Is(err, ErrNotRegular) {
+		t.Errorf("Remove of a directory = %v, want ErrNotRegular", err)
+	}
+	if _, _, err := l.Open("dir", 0); !errors.Is(err, ErrNotRegular) {
+		t.Errorf("Open of a directory = %v, want ErrNotRegular", err)
+	}
+}
+
+func TestRejectedNames(t *testing.T) {
+	for kind, s := range storages(t) {
+		t.Run(kind, func(t *testing.T) {
+			for name, want := range map[string]error{
+				"../x":          ErrOutsideRoot,
+				"/etc/passwd":   ErrOutsideRoot,
+				"dir/../../x":   ErrOutsideRoot,
+				".a.txt.sum":    ErrHidden,
+				"dir/.a.txt.v1": ErrHidden,
+				"dir/../.blobs": ErrHidden,
+			} {
+				if _, err := s.Create(name, 0); !errors.Is(err, want) {
+					t.Errorf("Create(%q) = %v, want %v", name, err, want)
+				}
+				if _, err := s.Stat(name); !errors.Is(err, want) {
+					t.Errorf("Stat(%q) = %v, want %v", name, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSubIsolatesNames(t *testing.T) {
+	for kind, parent := range map[string]Storage{"memory": NewMemory(), "local": mustLocal(t)} {
+		t.Run(kind, func(t *testing.T) {
+			alice, err := Sub(parent, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			bob, err := Sub(parent, "bob")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store(t, alice, "a.txt", "alice's", messages.ConflictPolicy_REJECT)
+			if _, _, err := store(t, bob, "a.txt", "bob's", messages.ConflictPolicy_REJECT); err != nil {
+				t.Errorf("bob's a.txt clashed with alice's: %v", err)
+			}
+
+			if got := read(t, parent, "alice/a.txt", 0); got != "alice's" {
+				t.Errorf("parent has %q under alice/a.txt", got)
+			}
+			infos, _, err := alice.List("", "", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(infos) != 1 || infos[0].Name != "a.txt" {
+				t.Errorf("alice lists %d entries", len(infos))
+			}
+
+			if _, err := alice.Stat("../bob/a.txt"); !errors.Is(err, ErrOutsideRoot) {
+				t.Errorf("alice reached bob's file: %v", err)
+			}
+		})
+	}
+}
+
+func TestExpireAbandonedUploads(t *testing.T) {
+	l := mustLocal(t)
+	for _, name := range []string{"old.txt", "dir/old.txt", "new.txt"} {
+		w, err := l.WriteRange(name, 5, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, "hello")
+		w.Close()
+	}
+	// An upload in progress keeps its partial file however old it is
+	busy, _, err := l.Resume("busy.txt", 5, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	old := time.Now().Add(-time.Hour)
+	for _, p := range []string{util.RangesName("old.txt"), util.RangesName("dir/old.txt"), util.PartialName("busy.txt")} {
+		if err := os.Chtimes(filepath.Join(l.Root().Dir(), p), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store(t, l, "kept.txt", "kept", messages.ConflictPolicy_REJECT)
+
+	if err := l.Expire(time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]bool{
+		util.RangesName("old.txt"):     false,
+		util.RangesName("dir/old.txt"): false,
+		util.RangesName("new.txt"):     true,
+		util.PartialName("busy.txt"):   true,
+	} {
+		_, err := os.Stat(filepath.Join(l.Root().Dir(), p))
+		if kept := err == nil; kept != want {
+			t.Errorf("%s kept = %v, want %v", p, kept, want)
+		}
+	}
+	if got := read(t, l, "kept.txt", 0); got != "kept" {
+		t.Errorf("stored file reads %q after Expire", got)
+	}
+}
+
+// mustLocal returns a Local storage in a fresh directory.
+func mustLocal(t *testing.T) *Local {
+	t.Helper()
+	local, err := NewLocal(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return local
+}
+
+func TestResumedUploadIsVerified(t *testing.T) {
+	l := mustLocal(t)
+	w, _, err := l.Resume("a.txt", 5, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "hel")
+	w.Close()
+
+	w, offset, err := l.Resume("a.txt", 5, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 3 {
+		t.Fatalf("Resume offset = %d, want 3", offset)
+	}
+	// Changed after it was checksummed for the resumed upload
+	if err := os.WriteFile(filepath.Join(l.Root().Dir(), util.PartialName("a.txt")), []byte("HEL"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "lo")
+
+	sum := sha256.Sum256([]byte("hello"))
+	check := &messages.ChecksumVerification{Algorithm: messages.HashAlgorithm_SHA256, Checksum: sum[:]}
+	if _, _, err := w.Commit(Attributes{Checksum: check}); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Commit = %v, want ErrCorrupt", err)
+	}
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BlobDir is the hidden directory of a deduplicating storage root that holds
+// each distinct file content once per permissions and modification time,
+// named by its SHA-256 followed by those. The stored files are hard links to
+// these blobs, so linking files with different metadata to the same blob
+// would change it for all of them.
+const BlobDir = ".blobs"
+
+// BlobName returns where the content with the given SHA-256, permissions and
+// modification time is kept under dir.
+func BlobName(dir string, sum []byte, mode fs.FileMode, modTime time.Time) string {
+	name := hex.EncodeToString(sum)
+	return filepath.Join(dir, BlobDir, name[:2], fmt.Sprintf("%s-%o-%d", name, mode.Perm(), modTime.Unix()))
+}
+
+// FindBlob returns a blob under dir with the given SHA-256, preferring one
+// with the given permissions and modification time, or "" if there is none.
+func FindBlob(dir string, sum []byte, mode fs.FileMode, modTime time.Time) string {
+	exact := BlobName(dir, sum, mode, modTime)
+	if _, err := os.Stat(exact); err == nil {
+		return exact
+	}
+
+	prefix := hex.EncodeToString(sum) + "-"
+	entries, err := os.ReadDir(filepath.Dir(exact))
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix) {
+			return filepath.Join(filepath.Dir(exact), entry.Name())
+		}
+	}
+	return ""
+}
+
+// LinkTemp makes a new hidden hard link to target next to fileName, for
+// renaming into place like a file from CreateTemp.
+func LinkTemp(target string, fileName string) (string, error) {
+	for {
+		name := tempName(fileName)
+		err := os.Link(target, name)
+		if !os.IsExist(err) {
+			return name, err
+		}
+	}
+}
+
+// SweepBlobs removes the blobs under dir that no stored file links to any
+// more.
+func SweepBlobs(dir string) error {
+	err := filepath.WalkDir(filepath.Join(dir, BlobDir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if links, ok := LinkCount(info); ok && links <= 1 {
+			return os.Remove(p)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"file-transfer/messages"
+)
+
+// ParseCompression maps a name such as "zstd" (case-insensitive) to its
+// Compression.
+func ParseCompression(name string) (messages.Compression, error) {
+	value, ok := messages.Compression_value[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown compression %q (want none, gzip or zstd)", name)
+	}
+	return messages.Compression(value), nil
+}
+
+// Compressions lists the compressions the messages package can apply, for
+// telling peers.
+func Compressions() []messages.Compression {
+	var compressions []messages.Compression
+	for value := range messages.Compression_name {
+		compressions = append(compressions, messages.Compression(value))
+	}
+	slices.Sort(compressions)
+	return compressions
+}
+
+// CompressionName returns the lower-case name used on the command line.
+func CompressionName(compression messages.Compression) string {
+	return strings.ToLower(compression.String())
+}
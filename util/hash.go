@@ -0,0 +1,84 @@
+package util
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"file-transfer/messages"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+)
+
+// NewHash returns a fresh hash for algorithm, or an error if this build does
+// not know it (e.g. a newer peer asked for something we can't compute).
+func NewHash(algorithm messages.HashAlgorithm) (hash.Hash, error) {
+	switch algorithm {
+	case messages.HashAlgorithm_MD5:
+		return md5.New(), nil
+	case messages.HashAlgorithm_SHA256:
+		return sha256.New(), nil
+	case messages.HashAlgorithm_BLAKE2B:
+		return blake2b.New256(nil)
+	case messages.HashAlgorithm_XXHASH:
+		return xxhash.New(), nil
+	case messages.HashAlgorithm_CRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %v", algorithm)
+	}
+}
+
+// Hashes lists the algorithms NewHash knows, for telling peers.
+func Hashes() []messages.HashAlgorithm {
+	var algorithms []messages.HashAlgorithm
+	for value := range messages.HashAlgorithm_name {
+		if _, err := NewHash(messages.HashAlgorithm(value)); err == nil {
+			algorithms = append(algorithms, messages.HashAlgorithm(value))
+		}
+	}
+	slices.Sort(algorithms)
+	return algorithms
+}
+
+// ParseHashAlgorithm maps a name such as "sha256" (case-insensitive) to its
+// algorithm.
+func ParseHashAlgorithm(name string) (messages.HashAlgorithm, error) {
+	value, ok := messages.HashAlgorithm_value[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown hash algorithm %q", name)
+	}
+	return messages.HashAlgorithm(value), nil
+}
+
+// HashAlgorithmName returns the lower-case name used on the command line and
+// in checksum sidecar files.
+func HashAlgorithmName(algorithm messages.HashAlgorithm) string {
+	return strings.ToLower(algorithm.String())
+}
+
+// HashFile returns the checksum of the whole file at fileName.
+func HashFile(fileName string, algorithm messages.HashAlgorithm) ([]byte, error) {
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
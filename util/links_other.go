@@ -0,0 +1,13 @@
+//go:build !unix
+
+package util
+
+import "io/fs"
+
+// CountsLinks reports whether LinkCount works on this platform.
+const CountsLinks = false
+
+// LinkCount returns the number of hard links to the file info describes.
+func LinkCount(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package util
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// CountsLinks reports whether LinkCount works on this platform.
+const CountsLinks = true
+
+// LinkCount returns the number of hard links to the file info describes.
+func LinkCount(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Nlink), true
+}
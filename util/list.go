@@ -0,0 +1,101 @@
+package util
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"file-transfer/messages"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// ChecksumName returns the hidden sidecar file that records the checksum of a
+// stored file so it can be reported by LIST without rereading the data.
+func ChecksumName(fileName string) string {
+	return filepath.Join(filepath.Dir(fileName), "."+filepath.Base(fileName)+".sum")
+}
+
+// SaveChecksum records the verified checksum of a stored file.
+func SaveChecksum(fileName string, checksum *messages.ChecksumVerification) error {
+	return os.WriteFile(ChecksumName(fileName), []byte(FormatChecksum(checksum)+"\n"), 0666)
+}
+
+// LoadChecksum returns the recorded checksum of a stored file, or nil if there
+// is none (e.g. the file was placed in the storage directory by hand).
+func LoadChecksum(fileName string) *messages.ChecksumVerification {
+	data, err := os.ReadFile(ChecksumName(fileName))
+	if err != nil {
+		return nil
+	}
+
+	return ParseChecksum(string(data))
+}
+
+// FormatChecksum renders a checksum as its algorithm and hex digest, e.g.
+// "md5 d41d8cd98f00b204e9800998ecf8427e".
+func FormatChecksum(checksum *messages.ChecksumVerification) string {
+	return HashAlgorithmName(checksum.GetAlgorithm()) + " " + hex.EncodeToString(checksum.GetChecksum())
+}
+
+// ParseChecksum parses what FormatChecksum returns, or nil if s is not a
+// checksum.
+func ParseChecksum(s string) *messages.ChecksumVerification {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil
+	}
+
+	algorithm, err := ParseHashAlgorithm(fields[0])
+	if err != nil {
+		return nil
+	}
+	checksum, err := hex.DecodeString(fields[1])
+	if err != nil {
+		return nil
+	}
+	return &messages.ChecksumVerification{Algorithm: algorithm, Checksum: checksum}
+}
+
+// ErrNotRegular is returned for names that refer to something other than a
+// file, such as a directory. The storage package hands it on as its own.
+var ErrNotRegular = errors.New("not a regular file")
+
+// RemoveFile deletes a stored regular file along with its checksum sidecar and
+// any older versions of it.
+func RemoveFile(fileName string) error {
+	info, err := os.Lstat(fileName)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return &fs.PathError{Op: "remove", Path: fileName, Err: ErrNotRegular}
+	}
+
+	if err := os.Remove(fileName); err != nil {
+		return err
+	}
+	os.Remove(ChecksumName(fileName))
+	return RemoveVersions(fileName)
+}
+
+// FormatFileEntry renders a LIST entry as a single line for the client CLIs.
+func FormatFileEntry(entry *messages.FileEntry) string {
+	modTime := time.Unix(entry.GetModTime(), 0).Format("2006-01-02 15:04:05")
+	checksum := "-"
+	if len(entry.GetChecksum()) > 0 {
+		checksum = HashAlgorithmName(entry.GetChecksumAlgorithm()) + ":" + hex.EncodeToString(entry.GetChecksum())
+	}
+	if entry.GetVersion() > 0 {
+		return fmt.Sprintf("%12d  %s  %-36s  %s (version %d)", entry.GetSize(), modTime, checksum, entry.GetName(), entry.GetVersion())
+	}
+	return fmt.Sprintf("%12d  %s  %-36s  %s", entry.GetSize(), modTime, checksum, entry.GetName())
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PartialName returns the hidden name used to hold an incomplete transfer of
+// fileName until its checksum has been verified.
+func PartialName(fileName string) string {
+	return filepath.Join(filepath.Dir(fileName), "."+filepath.Base(fileName)+".part")
+}
+
+// RangesName returns the hidden name a file uploaded in ranges over several
+// connections is put together under.
+func RangesName(fileName string) string {
+	return filepath.Join(filepath.Dir(fileName), "."+filepath.Base(fileName)+".ranges")
+}
+
+// IsPartial reports whether fileName is that of a partial or ranges file.
+func IsPartial(fileName string) bool {
+	base := filepath.Base(fileName)
+	return strings.HasPrefix(base, ".") && (strings.HasSuffix(base, ".part") || strings.HasSuffix(base, ".ranges"))
+}
+
+// OpenRanges opens (or creates) the file fileName is put together in from
+// ranges, sized to hold all of them. Ranges of the same upload can be written
+// into it at the same time; what was left from another upload of a different
+// size is cut off.
+func OpenRanges(fileName string, size uint64) (*os.File, error) {
+	file, err := os.OpenFile(RangesName(fileName), os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err == nil && uint64(info.Size()) != size {
+		err = file.Truncate(int64(size))
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// CreateTemp creates a new hidden file next to fileName to write its data
+// into until it is complete. Unlike the partial file, which only one upload
+// can use at a time, each call gets a file of its own, so concurrent transfers
+// of the same name don't write into each other.
+func CreateTemp(fileName string) (*os.File, error) {
+	for {
+		// Unlike os.CreateTemp, leave the permissions up to the umask
+		file, err := os.OpenFile(tempName(fileName), os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+		if !os.IsExist(err) {
+			return file, err
+		}
+	}
+}
+
+// tempName picks a random hidden name next to fileName.
+func tempName(fileName string) string {
+	return filepath.Join(filepath.Dir(fileName), fmt.Sprintf(".%s.%d.tmp", filepath.Base(fileName), rand.Uint32()))
+}
+
+// SyncDir flushes dir to disk, so that a file just renamed into it is still
+// there after a crash.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+	return nil
+}
+
+// OpenPartial opens (or creates) the partial file for fileName and feeds the
+// bytes it already holds through h, so the final checksum still covers the
+// whole file. The returned file is positioned at the end of the existing data
+// and the returned offset is its length. A partial file that is already larger
+// than size cannot belong to this transfer and is discarded.
+func OpenPartial(fileName string, size uint64, h hash.Hash) (*os.File, uint64, error) {
+	file, err := os.OpenFile(PartialName(fileName), os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	if uint64(info.Size()) > size {
+		if err := file.Truncate(0); err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		return file, 0, nil
+	}
+
+	offset, err := io.Copy(h, file)
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, uint64(offset), nil
+}
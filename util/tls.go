@@ -0,0 +1,111 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// ServerTLSConfig loads the server's certificate and key. If clientCAFile is
+// set, clients must also present a certificate signed by one of the CAs in it
+// (mutual TLS); otherwise any client may connect. With no files at all it
+// returns a nil config, meaning plain TCP.
+func ServerTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" && clientCAFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS needs both a certificate and a key file")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// ClientTLSConfig builds the configuration used to dial a TLS server, or
+// returns a nil config (plain TCP) if useTLS is false and no files are given.
+// The server is verified against caFile, or the system roots if it is empty.
+// If certFile and keyFile are set they are presented to servers that require
+// client certificates.
+func ClientTLSConfig(useTLS bool, caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	if !useTLS && caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("a client certificate needs both a cert and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Listen opens a TCP listener on port, wrapped in TLS if config is not nil.
+func Listen(port string, config *tls.Config) (net.Listener, error) {
+	if config == nil {
+		return net.Listen("tcp", ":"+port)
+	}
+	return tls.Listen("tcp", ":"+port, config)
+}
+
+// Dial connects to host, over TLS if config is not nil. The returned
+// connection can be handed to messages.NewMessageHandler either way.
+func Dial(ctx context.Context, host string, config *tls.Config) (net.Conn, error) {
+	if config == nil {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", host)
+	}
+	dialer := tls.Dialer{Config: config}
+	return dialer.DialContext(ctx, "tcp", host)
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", caFile)
+	}
+	return pool, nil
+}
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TreeFile is a regular file found under a directory being uploaded.
+type TreeFile struct {
+	Path string // Where the file is on disk
+	Name string // What to call it on the server
+	Info fs.FileInfo
+}
+
+// WalkTree returns the regular files under dir, named by their path relative
+// to dir's parent so the tree keeps its top-level name on the server. Hidden
+// files and directories are skipped since the server reserves such names, as
+// are symlinks and other special files; they are returned as the second
+// value, each with the reason it was left out.
+func WalkTree(dir string) ([]TreeFile, []string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent := filepath.Dir(abs)
+
+	var files []TreeFile
+	var skipped []string
+	err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(parent, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if p != abs && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				skipped = append(skipped, name+"/: hidden directory")
+				return filepath.SkipDir
+			}
+			skipped = append(skipped, name+": hidden file")
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			skipped = append(skipped, name+": symlink")
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			skipped = append(skipped, name+": not a regular file")
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, TreeFile{Path: p, Name: name, Info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return files, skipped, nil
+}
+
+// FileMode returns the permission bits of info in the form sent over the wire.
+func FileMode(info fs.FileInfo) uint32 {
+	return uint32(info.Mode().Perm())
+}
+
+// ApplyMetadata gives a transferred file the permissions and modification
+// time it had at the source. Zero values leave the file as it is.
+func ApplyMetadata(fileName string, mode uint32, modTime int64) error {
+	if mode != 0 {
+		if err := os.Chmod(fileName, fs.FileMode(mode).Perm()); err != nil {
+			return err
+		}
+	}
+	if modTime != 0 {
+		t := time.Unix(modTime, 0)
+		if err := os.Chtimes(fileName, t, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Summary tallies the outcome of a recursive transfer.
+type Summary struct {
+	succeeded int
+	failures  []string
+	skipped   []string
+}
+
+// Add records the result of transferring one file.
+func (s *Summary) Add(name string, err error) {
+	if err != nil {
+		s.failures = append(s.failures, fmt.Sprintf("%s: %v", name, err))
+		return
+	}
+	s.succeeded++
+}
+
+// Skip records an entry that was deliberately left out, and why.
+func (s *Summary) Skip(entry string) {
+	s.skipped = append(s.skipped, entry)
+}
+
+// Failed returns the number of files that could not be transferred.
+func (s *Summary) Failed() int {
+	return len(s.failures)
+}
+
+func (s *Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d files transferred, %d failed", s.succeeded, len(s.failures))
+	if len(s.skipped) > 0 {
+		fmt.Fprintf(&b, ", %d skipped", len(s.skipped))
+	}
+	for _, failure := range s.failures {
+		b.WriteString("\n  ")
+		b.WriteString(failure)
+	}
+	for _, entry := range s.skipped {
+		b.WriteString("\n  skipped ")
+		b.WriteString(entry)
+	}
+	return b.String()
+}
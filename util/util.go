@@ -1,18 +1,27 @@
 package util
 
 import (
-	"log"
-	"reflect"
+	"bytes"
+	"fmt"
+
+	"file-transfer/messages"
 )
 
-func VerifyChecksum(serverCheck []byte, clientCheck []byte) bool {
-	log.Printf("Server checksum: %x\n", serverCheck)
-	log.Printf("Client checksum: %x\n", clientCheck)
-	if reflect.DeepEqual(clientCheck, serverCheck) {
-		log.Println("Checksums match")
-		return true
-	} else {
-		log.Println("Checksums DO NOT match")
-		return false
+// VerifyChecksum reports whether both sides computed the same checksum with
+// the same algorithm.
+func VerifyChecksum(serverCheck *messages.ChecksumVerification, clientCheck *messages.ChecksumVerification) bool {
+	return serverCheck.GetAlgorithm() == clientCheck.GetAlgorithm() &&
+		bytes.Equal(serverCheck.GetChecksum(), clientCheck.GetChecksum())
+}
+
+// RangeLength returns how many bytes of a file of the given size are covered by
+// a request starting at offset. A length of zero means "to the end of the file".
+func RangeLength(size uint64, offset uint64, length uint64) (uint64, error) {
+	if offset > size {
+		return 0, fmt.Errorf("offset %d is beyond the end of the file (%d bytes)", offset, size)
+	}
+	if length == 0 || length > size-offset {
+		length = size - offset
 	}
+	return length, nil
 }
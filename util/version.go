@@ -0,0 +1,106 @@
+package util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"file-transfer/messages"
+)
+
+// VersionName returns the hidden file that holds version n of fileName once a
+// newer version has been stored. Versions are numbered from 1, oldest first.
+func VersionName(fileName string, n uint32) string {
+	return filepath.Join(filepath.Dir(fileName), fmt.Sprintf(".%s.v%d", filepath.Base(fileName), n))
+}
+
+// ParseVersionName splits the name of a version file made by VersionName into
+// the name of the file it is a version of and the version number.
+func ParseVersionName(p string) (string, uint32, bool) {
+	base := filepath.Base(p)
+	i := strings.LastIndex(base, ".v")
+	if i < 1 || !strings.HasPrefix(base, ".") {
+		return "", 0, false
+	}
+	n, err := strconv.ParseUint(base[i+2:], 10, 32)
+	if err != nil || n == 0 {
+		return "", 0, false
+	}
+	return filepath.Join(filepath.Dir(p), base[1:i]), uint32(n), true
+}
+
+// LatestVersion returns the number of the newest older version kept of
+// fileName, or 0 if there are none. The current contents of the file are
+// version LatestVersion+1.
+func LatestVersion(fileName string) (uint32, error) {
+	dir := filepath.Dir(fileName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	latest := uint32(0)
+	for _, entry := range entries {
+		name, n, ok := ParseVersionName(filepath.Join(dir, entry.Name()))
+		if ok && name == fileName && n > latest {
+			latest = n
+		}
+	}
+	return latest, nil
+}
+
+// VersionFile returns the file holding version n of fileName, which is
+// fileName itself for the current version.
+func VersionFile(fileName string, n uint32) (string, error) {
+	latest, err := LatestVersion(fileName)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case n == latest+1:
+		return fileName, nil
+	case n == 0 || n > latest:
+		return "", &fs.PathError{Op: "open", Path: fileName, Err: fmt.Errorf("no version %d: %w", n, fs.ErrNotExist)}
+	}
+	return VersionName(fileName, n), nil
+}
+
+// RemoveVersions deletes every older version kept of fileName.
+func RemoveVersions(fileName string) error {
+	latest, err := LatestVersion(fileName)
+	if err != nil {
+		return err
+	}
+	for n := uint32(1); n <= latest; n++ {
+		if err := os.Remove(VersionName(fileName, n)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// FreeName returns the first name of the form "report (1).pdf" next to
+// fileName that isn't taken yet.
+func FreeName(fileName string) string {
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			return name
+		}
+	}
+}
+
+// ParseConflictPolicy maps a name such as "keep-both" (case-insensitive) to
+// its ConflictPolicy.
+func ParseConflictPolicy(name string) (messages.ConflictPolicy, error) {
+	value, ok := messages.ConflictPolicy_value[strings.ToUpper(strings.ReplaceAll(name, "-", "_"))]
+	if !ok {
+		return 0, fmt.Errorf("unknown conflict policy %q (want reject, overwrite, keep-both or version)", name)
+	}
+	return messages.ConflictPolicy(value), nil
+}